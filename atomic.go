@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// 新しく作るファイルのパーミッション
+const defaultFileMode fs.FileMode = 0644
+
+// 同じディレクトリの一時ファイルに書いてから rename し、途中で止まっても壊れたファイルを残さない
+// 既にあるファイルならパーミッションを引き継ぎ、シンボリックリンクならリンク先を書き換える
+func WriteFileAtomic(path string, data []byte) error {
+	return WriteFileAtomicFunc(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFileAtomic と同じように、write が書いた内容でファイルを置き換える
+// 大きなファイルをメモリに溜めずに書き出すためのもので、write が失敗したらファイルは変わらない
+func WriteFileAtomicFunc(path string, write func(w io.Writer) error) error {
+	// rename でリンク自体を普通のファイルに置き換えないよう、リンク先に書く
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		target, err = danglingSymlinkTarget(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	mode := defaultFileMode
+	if info, err := os.Stat(target); err == nil {
+		if !info.Mode().IsRegular() {
+			return &fs.PathError{Op: "write", Path: target, Err: errors.New("not a regular file")}
+		}
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// rename まで進めば一時ファイルは無くなっているので、この削除は失敗してよい
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), mode)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), target)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// リンク先がまだ無いシンボリックリンクなら、そのリンク先を返す
+// シンボリックリンクでなければ path をそのまま返す
+func danglingSymlinkTarget(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return path, nil
+	}
+
+	link, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(path), link)
+	}
+	return link, nil
+}
+
+// rename したことをディスクに残すため、ディレクトリも fsync する
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	err = d.Sync()
+	// ディレクトリの fsync に対応していないファイルシステムもある
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
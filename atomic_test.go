@@ -0,0 +1,136 @@
+package main_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(dir string) string // 書き込むパスを返す
+		wantPath string                  // 内容が書き込まれるファイル
+		wantMode fs.FileMode
+	}{
+		{
+			name: "新しいファイルを作れる",
+			setup: func(dir string) string {
+				return filepath.Join(dir, "post.md")
+			},
+			wantPath: "post.md",
+			wantMode: 0644,
+		},
+		{
+			name: "既にあるファイルのパーミッションを引き継ぐ",
+			setup: func(dir string) string {
+				path := filepath.Join(dir, "post.md")
+				_ = os.WriteFile(path, []byte("old"), 0600)
+				return path
+			},
+			wantPath: "post.md",
+			wantMode: 0600,
+		},
+		{
+			name: "シンボリックリンクならリンク先を書き換える",
+			setup: func(dir string) string {
+				_ = os.WriteFile(filepath.Join(dir, "real.md"), []byte("old"), 0640)
+				path := filepath.Join(dir, "post.md")
+				_ = os.Symlink("real.md", path)
+				return path
+			},
+			wantPath: "real.md",
+			wantMode: 0640,
+		},
+		{
+			name: "リンク先の無いシンボリックリンクなら、リンク先を作る",
+			setup: func(dir string) string {
+				path := filepath.Join(dir, "post.md")
+				_ = os.Symlink("real.md", path)
+				return path
+			},
+			wantPath: "real.md",
+			wantMode: 0644,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			dir := t.TempDir()
+			path := tt.setup(dir)
+
+			// execute
+			err := WriteFileAtomic(path, []byte("new"))
+
+			// verify
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dir, tt.wantPath))
+			assert.NoError(t, err)
+			assert.Equal(t, "new", string(content))
+			info, err := os.Stat(filepath.Join(dir, tt.wantPath))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMode, info.Mode().Perm())
+			if tt.wantPath != "post.md" {
+				info, err := os.Lstat(path)
+				assert.NoError(t, err)
+				assert.NotZero(t, info.Mode()&fs.ModeSymlink)
+			}
+			// 一時ファイルが残っていない
+			entries, err := os.ReadDir(dir)
+			assert.NoError(t, err)
+			for _, entry := range entries {
+				assert.NotContains(t, entry.Name(), ".tmp")
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicError(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	dir := t.TempDir()
+
+	// execute
+	err := WriteFileAtomic(filepath.Join(dir, "missing", "post.md"), []byte("new"))
+
+	// verify
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestWriteFileAtomicFuncError(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	err := os.WriteFile(path, []byte("old"), 0644)
+	assert.NoError(t, err)
+	writeErr := errors.New("write failed")
+
+	// execute
+	err = WriteFileAtomicFunc(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("new"))
+		if err != nil {
+			return err
+		}
+		return writeErr
+	})
+
+	// verify
+	assert.ErrorIs(t, err, writeErr)
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(written))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,202 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// アーカイブの形式を変えたら上げる
+// 2 で投稿ごとのタグと添付ファイルの情報を加えた
+const FormatVersion = 2
+
+// tar.gz の中身は次の通り
+//
+//	manifest.json
+//	posts/<投稿の ID>.json
+//	meta/<投稿の ID>.json
+//	assets/<投稿の ID>/<ファイル名>
+type Manifest struct {
+	Version   int      `json:"version"`
+	CreatedAt string   `json:"created_at"`
+	Posts     []string `json:"posts"`
+}
+
+// fanbox-go の Post に無い、投稿の情報
+type Meta struct {
+	Tags    []string        `json:"tags"`
+	FileMap json.RawMessage `json:"file_map,omitempty"` // post.getEditable の fileMap をそのまま持つ
+}
+
+type Writer struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	manifest Manifest
+}
+
+// 読み込んだアーカイブの中身
+type Archive struct {
+	Manifest Manifest
+	Posts    []fanboxgo.Post
+	Metas    map[string]Meta              // 投稿の ID ごとの情報。バージョン 1 のアーカイブには無い
+	Assets   map[string]map[string][]byte // 投稿の ID、ファイル名ごとの中身
+}
+
+func NewWriter(w io.Writer) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{
+		gz: gz,
+		tw: tar.NewWriter(gz),
+		manifest: Manifest{
+			Version:   FormatVersion,
+			CreatedAt: time.Now().Format(time.RFC3339),
+			Posts:     []string{},
+		},
+	}
+}
+
+func (w *Writer) AddPost(post fanboxgo.Post) error {
+	if post.ID.Value == "" {
+		return errors.New("post id is empty")
+	}
+	bytes, err := post.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	err = w.write(path.Join("posts", post.ID.Value+".json"), bytes)
+	if err != nil {
+		return err
+	}
+	w.manifest.Posts = append(w.manifest.Posts, post.ID.Value)
+	return nil
+}
+
+func (w *Writer) AddMeta(postId string, meta Meta) error {
+	bytes, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return w.write(path.Join("meta", postId+".json"), bytes)
+}
+
+func (w *Writer) AddAsset(postId string, name string, data []byte) error {
+	if path.Base(name) != name {
+		return fmt.Errorf("invalid asset name: %s", name)
+	}
+	return w.write(path.Join("assets", postId, name), data)
+}
+
+// manifest.json を書き込んでから閉じる
+func (w *Writer) Close() error {
+	bytes, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = w.write("manifest.json", bytes)
+	if err != nil {
+		return err
+	}
+	err = w.tw.Close()
+	if err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+func (w *Writer) write(name string, data []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
+
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	archive := &Archive{
+		Metas:  map[string]Meta{},
+		Assets: map[string]map[string][]byte{},
+	}
+	posts := map[string]fanboxgo.Post{}
+	hasManifest := false
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(header.Name, "/")
+		switch {
+		case header.Name == "manifest.json":
+			err = json.Unmarshal(data, &archive.Manifest)
+			if err != nil {
+				return nil, err
+			}
+			hasManifest = true
+		case len(parts) == 2 && parts[0] == "posts":
+			post := fanboxgo.Post{}
+			err = post.UnmarshalJSON(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", header.Name, err)
+			}
+			posts[strings.TrimSuffix(parts[1], ".json")] = post
+		case len(parts) == 2 && parts[0] == "meta":
+			meta := Meta{}
+			err = json.Unmarshal(data, &meta)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", header.Name, err)
+			}
+			archive.Metas[strings.TrimSuffix(parts[1], ".json")] = meta
+		case len(parts) == 3 && parts[0] == "assets":
+			if archive.Assets[parts[1]] == nil {
+				archive.Assets[parts[1]] = map[string][]byte{}
+			}
+			archive.Assets[parts[1]][parts[2]] = data
+		}
+	}
+
+	if !hasManifest {
+		return nil, errors.New("manifest.json is not found")
+	}
+	if archive.Manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported archive version: %d", archive.Manifest.Version)
+	}
+
+	// manifest に書かれた順に並べる
+	for _, id := range archive.Manifest.Posts {
+		post, exist := posts[id]
+		if !exist {
+			return nil, fmt.Errorf("post %s is listed in manifest but not found", id)
+		}
+		archive.Posts = append(archive.Posts, post)
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,90 @@
+package backup_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync/backup"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		posts  []fanboxgo.Post
+		metas  map[string]Meta
+		assets map[string]map[string][]byte
+	}{
+		{
+			name: "書き込んだ投稿と添付ファイルを読み込める",
+			posts: []fanboxgo.Post{
+				{
+					ID:    fanboxgo.NewOptString("1000001"),
+					Title: fanboxgo.NewOptString("2番目の投稿"),
+				},
+				{
+					ID:    fanboxgo.NewOptString("1000000"),
+					Title: fanboxgo.NewOptString("最初の投稿"),
+					Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+						Blocks: []fanboxgo.PostBodyBlocksItem{
+							{
+								Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
+								ImageId: fanboxgo.NewOptString("img"),
+							},
+						},
+						ImageMap: fanboxgo.NewOptPostBodyImageMap(fanboxgo.PostBodyImageMap{
+							"img": {
+								ID:          fanboxgo.NewOptString("img"),
+								Extension:   fanboxgo.NewOptString("png"),
+								OriginalUrl: fanboxgo.NewOptString("https://example.com/img.png"),
+							},
+						}),
+					}),
+				},
+			},
+			metas: map[string]Meta{
+				"1000000": {
+					Tags:    []string{"イラスト"},
+					FileMap: json.RawMessage(`{"file":{"id":"file","name":"data","extension":"zip","url":"https://example.com/data.zip"}}`),
+				},
+			},
+			assets: map[string]map[string][]byte{
+				"1000000": {"img.png": []byte("png")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			buf := &bytes.Buffer{}
+			w := NewWriter(buf)
+			for _, post := range tt.posts {
+				assert.NoError(t, w.AddPost(post))
+			}
+			for postId, meta := range tt.metas {
+				assert.NoError(t, w.AddMeta(postId, meta))
+			}
+			for postId, files := range tt.assets {
+				for name, data := range files {
+					assert.NoError(t, w.AddAsset(postId, name, data))
+				}
+			}
+			assert.NoError(t, w.Close())
+
+			// execute
+			archive, err := Read(buf)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, FormatVersion, archive.Manifest.Version)
+			assert.Equal(t, tt.posts, archive.Posts)
+			assert.Equal(t, tt.metas, archive.Metas)
+			assert.Equal(t, tt.assets, archive.Assets)
+		})
+	}
+}
@@ -1,22 +1,70 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	"github.com/defaultcf/fanboxsync/backup"
+	"github.com/defaultcf/fanboxsync/epub"
 	"github.com/defaultcf/fanboxsync/fanbox"
-	"github.com/goccy/go-yaml"
+	"github.com/defaultcf/fanboxsync/history"
+	"github.com/defaultcf/fanboxsync/iframely"
 )
 
 func userAgent() string {
 	return fmt.Sprintf("fanboxsync/%s", version)
 }
 
-func CommandPull(config *config) error {
+// dry-run なら、変更を伴う API を呼ばずにリクエストを表示するだけのクライアントを返す
+func newFanbox(config *config, dryRun bool) (*fanbox.CustomFanbox, error) {
+	f, err := fanbox.NewFanbox(config.Default.CsrfToken, config.Default.SessionId, userAgent())
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return fanbox.NewDryRunFanbox(f, output.DryRunWriter()), nil
+	}
+	return f, nil
+}
+
+// 埋め込みの解決に使う iframely のクライアントを、キャッシュ付きで作る
+// キャッシュの置き場所が分からなければ、キャッシュせずに使う
+func newIframelyClient(offline bool, ttl time.Duration) (*iframely.IframelyClient, error) {
+	client := iframely.NewIframelyClient(&http.Client{})
+	client.Offline = offline
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		slog.Warn("embed cache is disabled", "error", err)
+		return client, nil
+	}
+	client.Cache = iframely.NewCache(filepath.Join(cacheDir, "fanboxsync", "iframely"), ttl)
+	return client, nil
+}
+
+func CommandPull(config *config, filter *PullFilter, format string, iframelyClient *iframely.IframelyClient) error {
+	_, err := LookupBodyFormat(format)
+	if err != nil {
+		return err
+	}
+
 	f, err := fanbox.NewFanbox(config.Default.CsrfToken, config.Default.SessionId, userAgent())
 	if err != nil {
 		return err
@@ -28,16 +76,40 @@ func CommandPull(config *config) error {
 	}
 
 	for _, v := range posts {
+		if !filter.Match(v) {
+			continue
+		}
+
+		// 種類のために取得するので、タグもここで確かめる
+		raw, err := f.GetRawPost(v.ID.Value)
+		if err != nil {
+			return err
+		}
+		if !filter.MatchTags(raw.Tags) {
+			continue
+		}
 		post, err := f.GetPost(v.ID.Value)
 		if err != nil {
 			return err
 		}
 
 		e := NewEntry("", "", "", "", "")
-		converted := e.ConvertPost(&post)
-		fmt.Printf("%+v\n", converted)
+		e.iframelyClient = iframelyClient
+		e.Format = format
+		converted, err := e.ConvertPost(&post)
+		if err != nil {
+			return err
+		}
+		if err := raw.Type.Validate(); err != nil {
+			slog.Warn("post type is not supported, the body may be empty and push is refused", "id", converted.ID, "type", raw.Type)
+		}
+		slog.Debug("converted", "entry", fmt.Sprintf("%+v", converted))
 
-		err = saveFile(*converted)
+		filePath, err := saveFile(".", *converted)
+		if err != nil {
+			return err
+		}
+		err = output.Print(Result{Action: "pulled", ID: converted.ID, Path: filePath, Title: converted.Title, Status: string(converted.Status)}, "pulled %s to %s", converted.ID, filePath)
 		if err != nil {
 			return err
 		}
@@ -46,139 +118,1369 @@ func CommandPull(config *config) error {
 	return nil
 }
 
-func CommandCreate(config *config, title string) error {
-	f, err := fanbox.NewFanbox(config.Default.CsrfToken, config.Default.SessionId, userAgent())
+var reCreatorId = regexp.MustCompile(`^[\w-]+$`)
+
+// 他のクリエイターの投稿を読み取り専用で保存する
+// 投稿はクリエイターの ID の名前のディレクトリに保存する
+func CommandArchive(f *fanbox.PublicFanbox, creatorId string, iframelyClient *iframely.IframelyClient) error {
+	// ディレクトリ名に使うので、他の場所を指せないよう英数字と - _ だけにする
+	if !reCreatorId.MatchString(creatorId) {
+		return fmt.Errorf("invalid creator id: %q", creatorId)
+	}
+
+	posts, err := f.GetCreatorPosts(creatorId)
 	if err != nil {
 		return err
 	}
 
+	err = os.MkdirAll(creatorId, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range posts {
+		// 1 件取得できなくても、残りの投稿は保存する
+		post, err := f.GetCreatorPost(v.Post.ID.Value)
+		if err != nil {
+			slog.Warn("failed to get post, skipped", "id", v.Post.ID.Value, "error", err)
+			err = output.Print(Result{Action: "skipped", ID: v.Post.ID.Value, Title: v.Post.Title.Value, Message: err.Error()}, "skipped %s", v.Post.ID.Value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		e := NewEntry("", "", "", "", "")
+		e.iframelyClient = iframelyClient
+		converted, err := e.ConvertPost(&post.Post)
+		if err != nil {
+			return err
+		}
+		converted.Restricted = post.IsRestricted
+		if converted.Restricted {
+			slog.Warn("post is restricted, body is not saved", "id", converted.ID)
+		}
+
+		filePath, err := saveFile(creatorId, *converted)
+		if err != nil {
+			return err
+		}
+		err = output.Print(Result{Action: "archived", ID: converted.ID, Path: filePath, Title: converted.Title}, "archived %s to %s", converted.ID, filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func CommandCreate(f *fanbox.CustomFanbox, title string, dryRun bool) error {
 	postId, err := f.CreatePost()
 	if err != nil {
 		return err
 	}
 
 	entry := NewEntry(postId, title, "draft", "0", "")
-	post := entry.ConvertFanbox(entry)
-	_, err = f.PushPost(post) // タイトルをセット
+	updated, err := pushEntry(f, entry, nil, nil) // タイトルをセット
+	if err != nil {
+		// ファイル名に使う日時がまだ無いので、作られた投稿は pull で取ってきてもらう
+		return fmt.Errorf("created %s but failed to set the title, run pull --id %s to get it: %w", postId, postId, err)
+	}
+
+	if dryRun {
+		return output.Print(Result{Action: "created", Title: title, DryRun: true}, "would create %q", title)
+	}
+
+	setUpdatedAt(f, entry, updated)
+	filePath, err := saveFile(".", *entry)
+	if err != nil {
+		return err
+	}
+
+	return output.Print(Result{Action: "created", ID: postId, Path: filePath, Title: title}, "created %s as %s", postId, filePath)
+}
+
+// 既存のファイルから投稿を作り、割り当てられた ID をそのファイルに書き戻す
+func CommandCreateFromFile(f *fanbox.CustomFanbox, path string, dryRun bool) error {
+	m, body, err := readFile(path)
 	if err != nil {
 		return err
 	}
+	if m.Id != "" {
+		return fmt.Errorf("%s already has id %s", path, m.Id)
+	}
+	if m.Title == "" {
+		return fmt.Errorf("title is empty")
+	}
+	if m.Status == "" {
+		m.Status = string(fanboxgo.PostStatusDraft)
+	}
+	if m.Fee == "" {
+		m.Fee = "0"
+	}
 
-	entry.UpdatedAt = time.Now().Format(time.RFC3339)
-	err = saveFile(*entry)
+	postId, err := f.CreatePost()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	m.Id = postId
+	entry := newEntryFromMeta(m, body)
+	// 本文を送れなくても投稿は作られているので、やり直しで同じ投稿を作らないよう先に ID を書き戻す
+	if !dryRun {
+		err = writeFile(path, *entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	updated, err := pushEntry(f, entry, nil, nil)
+	if err != nil {
+		return fmt.Errorf("created %s but failed to push, run push %s again: %w", postId, path, err)
+	}
+	if dryRun {
+		return output.Print(Result{Action: "created", Path: path, Title: entry.Title, DryRun: true}, "would create %q from %s", entry.Title, path)
+	}
+
+	setUpdatedAt(f, entry, updated)
+	err = writeFile(path, *entry)
+	if err != nil {
+		return err
+	}
+
+	return output.Print(Result{Action: "created", ID: postId, Path: path, Title: entry.Title}, "created %s from %s", postId, path)
 }
 
 type meta struct {
-	Id     string `yaml:"id"`
-	Title  string `yaml:"title"`
-	Status string `yaml:"status"`
-	Fee    string `yaml:"fee"`
+	Id         string            `yaml:"id" toml:"id"`
+	Title      string            `yaml:"title" toml:"title"`
+	Status     string            `yaml:"status" toml:"status"`
+	Fee        string            `yaml:"fee" toml:"fee"`
+	Restricted bool              `yaml:"restricted,omitempty" toml:"restricted,omitempty"`
+	Embeds     map[string]string `yaml:"embeds,omitempty" toml:"embeds,omitempty"`
+	UpdatedAt  string            `yaml:"updated_at,omitempty" toml:"updated_at,omitempty"` // pull した時点のサーバー上の更新日時
+	Format     string            `yaml:"format,omitempty" toml:"format,omitempty"`         // 無ければ拡張子から決める
 }
 
-func CommandPush(config *config, path string) error {
-	fi, err := os.Open(path)
+// pull してからサーバー上で投稿が更新されていたときのエラー
+type ConflictError struct {
+	ID              string
+	LocalUpdatedAt  string
+	RemoteUpdatedAt string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("post %s was updated at %s after it was pulled at %s, use --diff to see the changes or --force to overwrite them", e.ID, e.RemoteUpdatedAt, e.LocalUpdatedAt)
+}
+
+type PushOptions struct {
+	DryRun bool
+	Force  bool // サーバー上の変更を無視して上書きする
+	Diff   bool // 衝突したら、サーバー上の本文との差分を表示する
+}
+
+func CommandPush(f *fanbox.CustomFanbox, path string, opts PushOptions) error {
+	m, body, err := readFile(path)
 	if err != nil {
 		return err
 	}
-	defer fi.Close()
-	bytes, err := io.ReadAll(fi)
+	entry := newEntryFromMeta(m, body)
+
+	remote, err := f.GetPost(entry.ID)
+	if err != nil {
+		return err
+	}
+	// article 以外の本文は送れないので、サーバー上の投稿の種類で確かめる
+	raw, err := f.GetRawPost(entry.ID)
 	if err != nil {
 		return err
 	}
+	err = raw.Type.Validate()
+	if err != nil {
+		return fmt.Errorf("cannot push %s: %w", entry.ID, err)
+	}
 
-	// メタデータをマークダウンから抽出
-	rawBody := string(bytes)
-	reMeta := regexp.MustCompile(`---\n\n`)
-	splited := reMeta.Split(rawBody, 2)
-	m := meta{}
-	err = yaml.Unmarshal([]byte(splited[0]), &m)
+	// pull した時点から更新されていれば、上書きせずに止める
+	// マージには pull した時点の本文が要るが、手元にも履歴にも残していないので止めるだけにする
+	if m.UpdatedAt != "" && !opts.Force {
+		if remote.UpdatedAt.Value != m.UpdatedAt {
+			if opts.Diff {
+				converted, err := entry.ConvertPost(&remote)
+				if err != nil {
+					return err
+				}
+				diff := DiffLines(converted.Body, entry.Body)
+				err = output.Print(Result{Action: "diff", ID: entry.ID, Path: path, Message: diff}, "%s", diff)
+				if err != nil {
+					return err
+				}
+			}
+			return &ConflictError{
+				ID:              entry.ID,
+				LocalUpdatedAt:  m.UpdatedAt,
+				RemoteUpdatedAt: remote.UpdatedAt.Value,
+			}
+		}
+	}
+
+	if !opts.DryRun {
+		err = snapshot(f, entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	updated, err := pushEntry(f, entry, &remote, raw.Tags)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return output.Print(Result{Action: "pushed", ID: entry.ID, Path: path, Title: entry.Title, Status: string(entry.Status), DryRun: true}, "would push %s from %s", entry.ID, path)
+	}
+
+	setUpdatedAt(f, entry, updated)
+	err = writeFile(path, *entry)
 	if err != nil {
 		return err
 	}
 
-	entry := NewEntry(m.Id, m.Title, m.Status, m.Fee, string(splited[1]))
-	post := entry.ConvertFanbox(entry)
+	return output.Print(Result{Action: "pushed", ID: entry.ID, Path: path, Title: entry.Title, Status: string(entry.Status)}, "pushed %s from %s", entry.ID, path)
+}
 
-	f, err := fanbox.NewFanbox(config.Default.CsrfToken, config.Default.SessionId, userAgent())
+func newHistoryStore() (*history.Store, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewStore(filepath.Join(home, ".local", "share", "fanboxsync", "history")), nil
+}
+
+// 上書きする前に、サーバー上の投稿を履歴に残す
+func snapshot(f *fanbox.CustomFanbox, postId string) error {
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+	post, err := f.GetPost(postId)
+	if err != nil {
+		return err
+	}
+	if !post.ID.Set {
+		return nil // まだサーバー上に無い
+	}
+	revision, err := store.Save(post)
+	if err != nil {
+		return err
+	}
+	slog.Info("saved revision", "id", postId, "revision", revision)
+	return nil
+}
+
+func CommandHistory(target string) error {
+	postId, _, err := resolvePostId(target)
+	if err != nil {
+		return err
+	}
+	store, err := newHistoryStore()
 	if err != nil {
 		return err
 	}
 
-	_, err = f.PushPost(post)
+	revisions, err := store.List(postId)
 	if err != nil {
 		return err
 	}
+	for _, revision := range revisions {
+		post, err := store.Load(postId, revision)
+		if err != nil {
+			return err
+		}
+		err = output.Print(Result{Action: "revision", ID: postId, Revision: revision, Status: string(post.Status.Value), UpdatedAt: post.UpdatedAt.Value, Title: post.Title.Value},
+			"%s\t%s\t%s\t%s", revision, post.Status.Value, post.UpdatedAt.Value, post.Title.Value)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func CommandDelete(config *config, path string) error {
-	fi, err := os.Open(path)
+// 履歴に残した投稿で、サーバー上の投稿を上書きする
+type RollbackOptions struct {
+	DryRun        bool
+	RestoreStatus bool // 履歴にあるステータスにも戻す。無ければ今のステータスのままにする
+}
+
+func CommandRollback(f *fanbox.CustomFanbox, target string, revision string, opts RollbackOptions) error {
+	postId, _, err := resolvePostId(target)
 	if err != nil {
 		return err
 	}
-	defer fi.Close()
-	bytes, err := io.ReadAll(fi)
+	store, err := newHistoryStore()
+	if err != nil {
+		return err
+	}
+	post, err := store.Load(postId, revision)
 	if err != nil {
 		return err
 	}
 
-	rawBody := string(bytes)
-	reMeta := regexp.MustCompile(`---\n\n`)
-	splited := reMeta.Split(rawBody, 2)
-	m := meta{}
-	err = yaml.Unmarshal([]byte(splited[0]), &m)
+	// タグは履歴に残っていないので、今のタグを残す
+	raw, err := f.GetRawPost(postId)
+	if err != nil {
+		return err
+	}
+	err = raw.Type.Validate()
+	if err != nil {
+		return fmt.Errorf("cannot roll back %s: %w", postId, err)
+	}
+	// 本文を戻すだけで、公開した投稿を下書きに戻したりしない
+	if !opts.RestoreStatus {
+		current, err := f.GetPost(postId)
+		if err != nil {
+			return err
+		}
+		post.Status = current.Status
+	}
+	if !opts.DryRun {
+		err = snapshot(f, postId)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 埋め込みは FANBOX 上にあるものを使い、履歴に残した中身では上書きしない
+	post.Body.Value.UrlEmbedMap = fanboxgo.OptPostBodyUrlEmbedMap{}
+	_, err = f.PushPost(&post, raw.Tags)
 	if err != nil {
 		return err
 	}
+	if opts.DryRun {
+		return output.Print(Result{Action: "rolled_back", ID: postId, Revision: revision, Status: string(post.Status.Value), DryRun: true}, "would roll back %s to %s", postId, revision)
+	}
+	return output.Print(Result{Action: "rolled_back", ID: postId, Revision: revision, Status: string(post.Status.Value)}, "rolled back %s to %s, run pull to update the local file", postId, revision)
+}
 
-	f, err := fanbox.NewFanbox(config.Default.CsrfToken, config.Default.SessionId, userAgent())
+// 投稿を静的サイトジェネレーター向けの記事として、画像ごと outDir に書き出す
+func CommandExport(config *config, outDir string, opts ExportOptions, iframelyClient *iframely.IframelyClient) error {
+	err := opts.Validate()
+	if err != nil {
+		return err
+	}
+
+	f, err := newFanbox(config, false)
 	if err != nil {
 		return err
 	}
-	err = f.DeletePost(m.Id)
+	downloader := fanbox.NewPublicFanbox(&http.Client{}, config.Default.SessionId, userAgent())
+
+	posts, err := f.GetPosts()
 	if err != nil {
 		return err
 	}
 
+	for _, v := range posts {
+		post, err := f.GetPost(v.ID.Value)
+		if err != nil {
+			return err
+		}
+
+		raw, err := f.GetRawPost(v.ID.Value)
+		if err != nil {
+			return err
+		}
+
+		e := NewEntry("", "", "", "", "")
+		e.iframelyClient = iframelyClient
+		converted, err := e.ConvertPost(&post)
+		if err != nil {
+			return err
+		}
+		sitePost, err := RenderSitePost(*converted, raw.Tags, opts)
+		if err != nil {
+			return err
+		}
+		if sitePost == nil {
+			err = output.Print(Result{Action: "skipped", ID: converted.ID, Title: converted.Title, Message: "paid post"}, "skipped %s", converted.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = writeExportFile(filepath.Join(outDir, sitePost.Path), []byte(sitePost.Content))
+		if err != nil {
+			return err
+		}
+		for src, dest := range sitePost.Assets {
+			data, err := downloader.Download(src)
+			if err != nil {
+				return err
+			}
+			err = writeExportFile(filepath.Join(outDir, dest), data)
+			if err != nil {
+				return err
+			}
+		}
+		err = output.Print(Result{Action: "exported", ID: converted.ID, Path: filepath.Join(outDir, sitePost.Path), Title: converted.Title}, "exported %s", sitePost.Path)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// YYYY-MM-DD-ID.txt の形で、現在のディレクトリにファイルを保存する
-func saveFile(entry Entry) error {
-	parsedTime, err := time.Parse(time.RFC3339, entry.UpdatedAt)
+func writeExportFile(filePath string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
 	if err != nil {
 		return err
 	}
-	filePath := fmt.Sprintf("%s-%s.md", parsedTime.Format(time.DateOnly), entry.ID)
+	return os.WriteFile(filePath, data, 0644)
+}
 
-	meta := &meta{
-		Id:     entry.ID,
-		Title:  entry.Title,
-		Status: string(entry.Status),
-		Fee:    string(entry.Fee),
+// 対象の投稿の本文を、公開日時の古い順に取得する
+func getFilteredPosts(f *fanbox.CustomFanbox, filter *PullFilter) ([]fanboxgo.Post, error) {
+	posts, err := f.GetPosts()
+	if err != nil {
+		return nil, err
 	}
-	metaBytes, err := yaml.Marshal(meta)
+
+	posts, err = filterPostsByTag(f, posts, filter)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	result := []fanboxgo.Post{}
+	for _, v := range posts {
+		if !filter.Match(v) {
+			continue
+		}
+		post, err := f.GetPost(v.ID.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, post)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].PublishedAt.Value < result[j].PublishedAt.Value })
+
+	return result, nil
+}
+
+// タグは一覧に含まれないので、filter にタグがあるときだけ投稿ごとに取得して絞り込む
+func filterPostsByTag(f *fanbox.CustomFanbox, posts []fanboxgo.Post, filter *PullFilter) ([]fanboxgo.Post, error) {
+	if filter.Tag == "" {
+		return posts, nil
+	}
+
+	result := []fanboxgo.Post{}
+	for _, v := range posts {
+		// タグを取得する前に、一覧で分かる条件で減らしておく
+		if !filter.Match(v) {
+			continue
+		}
+		raw, err := f.GetRawPost(v.ID.Value)
+		if err != nil {
+			return nil, err
+		}
+		if filter.MatchTags(raw.Tags) {
+			result = append(result, v)
+		}
 	}
-	metaString := fmt.Sprintf("---\n%s---\n", string(metaBytes))
+	return result, nil
+}
 
-	f, err := os.Create(filePath)
+// 投稿ごとに HTML のページを書き出し、画像は images/<投稿の ID>/ に置く
+func CommandHTML(config *config, outDir string, filter *PullFilter, iframelyClient *iframely.IframelyClient) error {
+	f, err := newFanbox(config, false)
 	if err != nil {
-		return nil
+		return err
 	}
-	defer f.Close()
+	downloader := fanbox.NewPublicFanbox(&http.Client{}, config.Default.SessionId, userAgent())
 
-	_, err = f.Write([]byte(strings.Join([]string{metaString, entry.Body}, "\n")))
+	posts, err := getFilteredPosts(f, filter)
 	if err != nil {
 		return err
 	}
 
+	for _, post := range posts {
+		images := map[string]string{}
+		for imageId, image := range post.Body.Value.ImageMap.Value {
+			data, err := downloader.Download(image.OriginalUrl.Value)
+			if err != nil {
+				return err
+			}
+			src := path.Join("images", post.ID.Value, fmt.Sprintf("%s.%s", imageId, image.Extension.Value))
+			err = writeExportFile(filepath.Join(outDir, filepath.FromSlash(src)), data)
+			if err != nil {
+				return err
+			}
+			images[imageId] = src
+		}
+
+		e := NewEntry("", "", "", "", "")
+		e.iframelyClient = iframelyClient
+		body, err := e.ConvertHTML(&post, images)
+		if err != nil {
+			return err
+		}
+		htmlPath := filepath.Join(outDir, post.ID.Value+".html")
+		err = writeExportFile(htmlPath, []byte(HTMLPage(post.Title.Value, body)))
+		if err != nil {
+			return err
+		}
+		err = output.Print(Result{Action: "exported", ID: post.ID.Value, Path: htmlPath, Title: post.Title.Value}, "exported %s.html", post.ID.Value)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// 対象の投稿を 1 冊の EPUB にまとめる
+func CommandEPUB(config *config, outPath string, title string, filter *PullFilter, iframelyClient *iframely.IframelyClient) error {
+	f, err := newFanbox(config, false)
+	if err != nil {
+		return err
+	}
+	downloader := fanbox.NewPublicFanbox(&http.Client{}, config.Default.SessionId, userAgent())
+
+	posts, err := getFilteredPosts(f, filter)
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return fmt.Errorf("no posts matched")
+	}
+
+	book := epub.NewBook(fmt.Sprintf("urn:fanboxsync:%s:%d", config.Default.CreatorId, time.Now().Unix()), title, config.Default.CreatorId)
+	for _, post := range posts {
+		images := map[string]string{}
+		for imageId, image := range post.Body.Value.ImageMap.Value {
+			data, err := downloader.Download(image.OriginalUrl.Value)
+			if err != nil {
+				return err
+			}
+			src, err := book.AddImage(fmt.Sprintf("%s-%s.%s", post.ID.Value, imageId, image.Extension.Value), data)
+			if err != nil {
+				return err
+			}
+			images[imageId] = src
+		}
+
+		e := NewEntry("", "", "", "", "")
+		e.iframelyClient = iframelyClient
+		body, err := e.ConvertHTML(&post, images)
+		if err != nil {
+			return err
+		}
+		book.AddChapter(post.ID.Value, post.Title.Value, HTMLPage(post.Title.Value, body))
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	err = book.Write(out)
+	if err != nil {
+		return err
+	}
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+
+	return output.Print(Result{Action: "exported", Path: outPath, Title: title, Message: fmt.Sprintf("%d posts", len(posts))}, "exported %d posts to %s", len(posts), outPath)
+}
+
+// 他のサービスの投稿を下書きとして作り、元の ID と FANBOX の ID の対応を表示する
+func CommandImport(config *config, format ImportFormat, path string, reportPath string, dryRun bool) error {
+	imported, err := ImportPosts(format, path)
+	if err != nil {
+		return err
+	}
+
+	f, err := newFanbox(config, dryRun)
+	if err != nil {
+		return err
+	}
+
+	// 途中で失敗しても作った下書きが分かるよう、1 件ごとに書き足す
+	var report *csv.Writer
+	if reportPath != "" && !dryRun {
+		out, err := os.Create(reportPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		report = csv.NewWriter(out)
+		err = writeReportRow(report, []string{"source_id", "fanbox_id", "title"})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, v := range imported {
+		postId, err := f.CreatePost()
+		if err != nil {
+			return err
+		}
+		v.Entry.ID = postId
+		if report != nil {
+			err = writeReportRow(report, []string{v.SourceID, postId, v.Entry.Title})
+			if err != nil {
+				return err
+			}
+		}
+		updated, err := pushEntry(f, v.Entry, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var filePath string
+		if !dryRun {
+			setUpdatedAt(f, v.Entry, updated)
+			filePath, err = saveFile(".", *v.Entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = output.Print(Result{Action: "imported", ID: postId, SourceID: v.SourceID, Path: filePath, Title: v.Entry.Title, DryRun: dryRun},
+			"%s\t%s\t%s", v.SourceID, postId, v.Entry.Title)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeReportRow(w *csv.Writer, row []string) error {
+	err := w.Write(row)
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// 投稿をそのままの JSON と画像、添付ファイルごと、tar.gz にまとめて保存する
+func CommandBackup(config *config, outPath string) error {
+	f, err := newFanbox(config, false)
+	if err != nil {
+		return err
+	}
+	downloader := fanbox.NewPublicFanbox(&http.Client{}, config.Default.SessionId, userAgent())
+
+	posts, err := f.GetPosts()
+	if err != nil {
+		return err
+	}
+
+	// 途中で失敗しても前のバックアップを壊さないよう、一時ファイルに書いてから置き換える
+	return WriteFileAtomicFunc(outPath, func(out io.Writer) error {
+		w := backup.NewWriter(out)
+		for _, v := range posts {
+			err := backupPost(f, downloader, w, v.ID.Value, outPath)
+			if err != nil {
+				return err
+			}
+		}
+		return w.Close()
+	})
+}
+
+// fanbox-go の Post に無い、添付ファイルの情報
+type backupFile struct {
+	ID        string `json:"id"`
+	Extension string `json:"extension"`
+	URL       string `json:"url"`
+}
+
+func backupPost(f *fanbox.CustomFanbox, downloader *fanbox.PublicFanbox, w *backup.Writer, postId string, outPath string) error {
+	post, err := f.GetPost(postId)
+	if err != nil {
+		return err
+	}
+	raw, err := f.GetRawPost(postId)
+	if err != nil {
+		return err
+	}
+	err = w.AddPost(post)
+	if err != nil {
+		return err
+	}
+	err = w.AddMeta(postId, backup.Meta{Tags: raw.Tags, FileMap: raw.Body.FileMap})
+	if err != nil {
+		return err
+	}
+
+	for imageId, image := range post.Body.Value.ImageMap.Value {
+		data, err := downloader.Download(image.OriginalUrl.Value)
+		if err != nil {
+			return err
+		}
+		err = w.AddAsset(postId, fmt.Sprintf("%s.%s", imageId, image.Extension.Value), data)
+		if err != nil {
+			return err
+		}
+	}
+	if len(raw.Body.FileMap) > 0 {
+		files := map[string]backupFile{}
+		err = json.Unmarshal(raw.Body.FileMap, &files)
+		if err != nil {
+			return fmt.Errorf("invalid file map of %s: %w", postId, err)
+		}
+		for fileId, file := range files {
+			data, err := downloader.Download(file.URL)
+			if err != nil {
+				return err
+			}
+			err = w.AddAsset(postId, fmt.Sprintf("%s.%s", fileId, file.Extension), data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return output.Print(Result{Action: "backed_up", ID: postId, Path: outPath, Title: post.Title.Value}, "backed up %s", postId)
+}
+
+// バックアップから投稿を作り直す
+// 画像と添付ファイルはアップロードできないため除き、下書きとして作る
+func CommandRestore(f *fanbox.CustomFanbox, archivePath string, dryRun bool) error {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	archive, err := backup.Read(in)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range archive.Posts {
+		oldId := post.ID.Value
+		newId, err := f.CreatePost()
+		if err != nil {
+			return err
+		}
+
+		meta, exist := archive.Metas[oldId]
+		if !exist {
+			slog.Warn("tags are not in the archive, restore them by hand", "id", oldId)
+		}
+		if len(meta.FileMap) > 0 {
+			slog.Warn("files are skipped, upload them again from the archive", "id", oldId, "dir", path.Join("assets", oldId))
+		}
+		post.Body = fanboxgo.NewOptPostBody(restoreBody(oldId, post.Body.Value))
+		post.ID = fanboxgo.NewOptString(newId)
+		post.Status = fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft)
+
+		_, err = f.PushPost(&post, meta.Tags)
+		if err != nil {
+			return err
+		}
+		err = output.Print(Result{Action: "restored", ID: newId, SourceID: oldId, Title: post.Title.Value, DryRun: dryRun}, "%s\t%s", oldId, newId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 作り直した投稿で送れる本文にする
+// 画像や多くの埋め込みは元の投稿にしか無いため、画像は除き、FANBOX の投稿以外の埋め込みはリンク先の URL の段落にする
+func restoreBody(postId string, body fanboxgo.PostBody) fanboxgo.PostBody {
+	restored := fanboxgo.PostBody{Blocks: []fanboxgo.PostBodyBlocksItem{}}
+	urlEmbedMap := fanboxgo.PostBodyUrlEmbedMap{}
+	e := &Entry{}
+	for _, block := range body.Blocks {
+		switch block.Type.Value {
+		case fanboxgo.PostBodyBlocksItemTypeImage:
+			image := body.ImageMap.Value[block.ImageId.Value]
+			slog.Warn("image is skipped, upload it again from the archive", "id", postId, "asset", path.Join("assets", postId, fmt.Sprintf("%s.%s", block.ImageId.Value, image.Extension.Value)))
+			continue
+		case fanboxgo.PostBodyBlocksItemTypeURLEmbed:
+			embedId := block.UrlEmbedId.Value
+			embed, exist := body.UrlEmbedMap.Value[embedId]
+			if !exist {
+				slog.Warn("embed is skipped, it is not in the archive", "id", postId, "embed", embedId)
+				continue
+			}
+			if embed.Type.Value == fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost {
+				urlEmbedMap[embedId] = embed
+				break
+			}
+			url, err := e.getEmbedUrl(embed.Type.Value, embed)
+			if err != nil {
+				slog.Warn("embed is skipped", "id", postId, "error", err)
+				continue
+			}
+			slog.Warn("embed is restored as a link, embed it again by hand", "id", postId, "url", url)
+			block = fanboxgo.PostBodyBlocksItem{
+				Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+				Text: fanboxgo.NewOptString(url),
+			}
+		}
+		restored.Blocks = append(restored.Blocks, block)
+	}
+	if len(urlEmbedMap) > 0 {
+		restored.UrlEmbedMap = fanboxgo.NewOptPostBodyUrlEmbedMap(urlEmbedMap)
+	}
+	return restored
+}
+
+// 次の push で衝突と見なされないよう、更新した結果の日時を記録する
+// 更新自体は済んでいるので、日時が返ってこなくても失敗にはせず、取得し直すか前の日時のままにする
+func setUpdatedAt(f *fanbox.CustomFanbox, entry *Entry, updated fanboxgo.Post) {
+	if updated.UpdatedAt.Value != "" {
+		entry.UpdatedAt = updated.UpdatedAt.Value
+		return
+	}
+
+	slog.Warn("update response has no updated_at, fetching the post again", "id", entry.ID)
+	post, err := f.GetPost(entry.ID)
+	if err != nil || post.UpdatedAt.Value == "" {
+		slog.Warn("updated_at is unknown, run pull before the next push", "id", entry.ID, "error", err)
+		return
+	}
+	entry.UpdatedAt = post.UpdatedAt.Value
+}
+
+// 本文を送る
+// remote と tags には更新する前の投稿とタグを渡す。作ったばかりの投稿なら nil でよい
+func pushEntry(f *fanbox.CustomFanbox, entry *Entry, remote *fanboxgo.Post, tags []string) (fanboxgo.Post, error) {
+	// FANBOX 上にある埋め込みだけを、埋め込みとして送る
+	entry.RemoteEmbeds = map[string]bool{}
+	if remote != nil {
+		for embedId := range remote.Body.Value.UrlEmbedMap.Value {
+			entry.RemoteEmbeds[embedId] = true
+		}
+	}
+
+	post, err := entry.ConvertFanbox(entry)
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+	// 画像はアップロードできないので、FANBOX 上に無い画像は送らずに除く
+	var remoteImages fanboxgo.PostBodyImageMap
+	if remote != nil {
+		remoteImages = remote.Body.Value.ImageMap.Value
+	}
+	blocks := []fanboxgo.PostBodyBlocksItem{}
+	for _, block := range post.Body.Value.Blocks {
+		if block.Type.Value == fanboxgo.PostBodyBlocksItemTypeImage {
+			if _, exist := remoteImages[block.ImageId.Value]; !exist {
+				slog.Warn("image is not uploaded to FANBOX, skipped", "id", entry.ID, "image", block.ImageId.Value)
+				continue
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	post.Body.Value.Blocks = blocks
+	// 新しい埋め込みと一緒に送ると urlEmbedMap が置き換わるので、FANBOX 上にある埋め込みも送り返す
+	if remote != nil && len(post.Body.Value.UrlEmbedMap.Value) > 0 {
+		urlEmbedMap := maps.Clone(remote.Body.Value.UrlEmbedMap.Value)
+		if urlEmbedMap == nil {
+			urlEmbedMap = fanboxgo.PostBodyUrlEmbedMap{}
+		}
+		maps.Copy(urlEmbedMap, post.Body.Value.UrlEmbedMap.Value)
+		post.Body.Value.UrlEmbedMap = fanboxgo.NewOptPostBodyUrlEmbedMap(urlEmbedMap)
+	}
+	return f.PushPost(post, tags)
+}
+
+type DeleteOptions struct {
+	Yes      bool      // 確認せずに削除する
+	DryRun   bool      // 削除せずに、何が削除されるかだけ表示する
+	Force    bool      // 公開済みの投稿も削除する
+	TrashDir string    // 空でなければ、手元のファイルをここに移す
+	Dir      string    // ID で指定されたとき、手元のファイルを探すディレクトリ
+	Stdin    io.Reader // 確認の答えを読む。nil なら標準入力
+}
+
+// target にはファイルのパスか、投稿の ID を渡す
+func CommandDelete(f *fanbox.CustomFanbox, target string, opts DeleteOptions) error {
+	postId, localPath, err := resolvePostId(target)
+	if err != nil {
+		return err
+	}
+	if localPath == "" && opts.TrashDir != "" {
+		paths, err := localPostPaths(opts.Dir)
+		if err != nil {
+			return err
+		}
+		localPath = paths[postId]
+	}
+
+	post, err := f.GetPost(postId)
+	if err != nil {
+		return err
+	}
+	if post.Status.Value == fanboxgo.PostStatusPublished && !opts.Force {
+		return fmt.Errorf("%s is published, use --force to delete it", postId)
+	}
+
+	summary := fmt.Sprintf("%s %q (%s)", postId, post.Title.Value, post.Status.Value)
+	if !opts.DryRun && !opts.Yes {
+		stdin := opts.Stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		// 結果の出力に混ざらないよう、確認は標準エラー出力に出す
+		ok, err := confirm(stdin, os.Stderr, fmt.Sprintf("delete %s?", summary))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return output.Print(Result{Action: "canceled", ID: postId}, "canceled")
+		}
+	}
+
+	err = f.DeletePost(postId)
+	if err != nil {
+		return err
+	}
+	result := Result{Action: "deleted", ID: postId, Title: post.Title.Value, Status: string(post.Status.Value), DryRun: opts.DryRun}
+	if opts.DryRun {
+		err = output.Print(result, "would delete %s", summary)
+	} else {
+		err = output.Print(result, "deleted %s", summary)
+	}
+	if err != nil {
+		return err
+	}
+
+	if localPath != "" && opts.TrashDir != "" {
+		trashPath, err := unusedPath(opts.TrashDir, filepath.Base(localPath))
+		if err != nil {
+			return err
+		}
+		result := Result{Action: "moved", ID: postId, Path: trashPath, DryRun: opts.DryRun}
+		if opts.DryRun {
+			return output.Print(result, "would move %s to %s", localPath, trashPath)
+		}
+		err = os.MkdirAll(opts.TrashDir, 0755)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(localPath, trashPath)
+		if err != nil {
+			return err
+		}
+		return output.Print(result, "moved %s to %s", localPath, trashPath)
+	}
+
+	return nil
+}
+
+// dir に name が既にあれば、上書きしないよう -1, -2 と番号を付けたパスを返す
+func unusedPath(dir string, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		_, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+	}
+}
+
+// 投稿のステータスだけを変更し、手元のファイルがあればそのステータスも合わせる
+func CommandUpdateStatus(config *config, target string, status fanboxgo.PostStatus, dryRun bool) error {
+	postId, localPath, err := resolvePostId(target)
+	if err != nil {
+		return err
+	}
+
+	f, err := newFanbox(config, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		err = snapshot(f, postId)
+		if err != nil {
+			return err
+		}
+	}
+
+	verb := "publish"
+	if status != fanboxgo.PostStatusPublished {
+		verb = "unpublish"
+	}
+	updated, err := f.UpdateStatus(postId, status)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return output.Print(Result{Action: verb + "ed", ID: postId, Path: localPath, Status: string(status), DryRun: true}, "would %s %s", verb, postId)
+	}
+
+	if localPath != "" {
+		m, body, err := readFile(localPath)
+		if err != nil {
+			return err
+		}
+		m.Status = string(status)
+		entry := newEntryFromMeta(m, body)
+		setUpdatedAt(f, entry, updated)
+		err = writeFile(localPath, *entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return output.Print(Result{Action: verb + "ed", ID: postId, Path: localPath, Status: string(status)}, "%sed %s", verb, postId)
+}
+
+// push する前にファイルを確認する
+// 問題が見つかればエラーを返すので、CI ではそのまま失敗にできる
+func CommandLint(config *config, paths []string, opts LintOptions) error {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// config があれば、FANBOX にアップロードされている画像も確かめる
+	if config != nil {
+		f, err := newFanbox(config, false)
+		if err != nil {
+			return err
+		}
+		opts.RemoteImages = func(postId string) (map[string]bool, error) {
+			post, err := f.GetPost(postId)
+			if err != nil {
+				return nil, err
+			}
+			images := map[string]bool{}
+			for imageId := range post.Body.Value.ImageMap.Value {
+				images[imageId] = true
+			}
+			return images, nil
+		}
+	}
+
+	issues, err := LintFiles(paths, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		err = output.Print(issue, "%s", issue)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%d problems found", len(issues))
+	}
+	return nil
+}
+
+type watchOptions struct {
+	WatchOptions
+	Published bool // 下書きだけでなく、公開済みの投稿も push する
+}
+
+// ディレクトリを監視し、保存された下書きを push する
+func CommandWatch(ctx context.Context, f *fanbox.CustomFanbox, dir string, opts watchOptions) error {
+	// push で書き換えたファイルや、変わっていないファイルの保存では push しない
+	// handle は Watch の中で 1 つずつ呼ばれるので、ロックは要らない
+	lastContents := map[string]string{}
+
+	handle := func(path string) error {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		content := string(bytes)
+		if lastContents[path] == content {
+			return nil
+		}
+		lastContents[path] = content
+
+		issues, id, err := LintFile(path, content, LintOptions{})
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			for _, issue := range issues {
+				err = output.Print(issue, "%s", issue)
+				if err != nil {
+					return err
+				}
+			}
+			message := fmt.Sprintf("%d problems found", len(issues))
+			return output.Print(Result{Action: "skipped", ID: id, Path: path, Message: message}, "%s: not pushed, %s", path, message)
+		}
+		m, _, _, err := parseFile(path, content)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			message := "run create first"
+			return output.Print(Result{Action: "skipped", Path: path, Message: message}, "%s: not pushed, %s", path, message)
+		}
+		if m.Status != string(fanboxgo.PostStatusDraft) && !opts.Published {
+			message := fmt.Sprintf("%s is %s", id, m.Status)
+			return output.Print(Result{Action: "skipped", ID: id, Path: path, Status: m.Status, Message: message}, "%s: not pushed, %s", path, message)
+		}
+
+		err = CommandPush(f, path, PushOptions{})
+		if err != nil {
+			// サーバー上で更新されていれば、やり直しても push できない
+			var conflictErr *ConflictError
+			if errors.As(err, &conflictErr) {
+				return output.Print(Result{Action: "skipped", ID: id, Path: path, Message: err.Error()}, "%s: not pushed, %s", path, err)
+			}
+			// やり直せるよう、保存した内容を覚えておかない
+			delete(lastContents, path)
+			return err
+		}
+
+		// push で updated_at が書き換わるので、書き換えた後の内容を覚えておく
+		bytes, err = os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lastContents[path] = string(bytes)
+		return nil
+	}
+
+	return Watch(ctx, dir, opts.WatchOptions, handle)
+}
+
+// push で送るブロックからファイルを表示するサーバーを立てる
+// ファイルを保存するたびに、開いているブラウザが読み込み直す
+func CommandPreview(ctx context.Context, path string, addr string) error {
+	_, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	server := NewPreviewServer(path)
+	httpServer := &http.Server{Addr: addr, Handler: server}
+
+	// サーバーが先に止まったときに、監視も止める
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	target := previewPath(path)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- Watch(ctx, filepath.Dir(path), WatchOptions{Debounce: 100 * time.Millisecond}, func(p string) error {
+			if previewPath(p) == target {
+				server.Reload()
+			}
+			return nil
+		})
+	}()
+
+	url := fmt.Sprintf("http://%s/", addr)
+	err = output.Print(Result{Action: "serving", Path: path, Message: url}, "previewing %s on %s", path, url)
+	if err != nil {
+		return err
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-watchErr:
+		// 止められたときも、監視に失敗したときもサーバーを止める
+		httpServer.Close()
+		<-serveErr
+		return err
+	case err = <-serveErr:
+		cancel()
+		<-watchErr
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+type ListOptions struct {
+	Sort    ListSort
+	Reverse bool
+	Dir     string // 手元のファイルを探すディレクトリ
+	CSV     bool
+}
+
+// サーバー上の投稿を一覧にする
+func CommandList(f *fanbox.CustomFanbox, filter *PullFilter, opts ListOptions) error {
+	err := opts.Sort.Validate()
+	if err != nil {
+		return err
+	}
+	// 黙ってどちらかを選ばないよう、両方は指定させない
+	if opts.CSV && output.Format == OutputFormatJSON {
+		return errors.New("--csv cannot be used with --output json")
+	}
+
+	posts, err := f.GetPosts()
+	if err != nil {
+		return err
+	}
+	posts, err = filterPostsByTag(f, posts, filter)
+	if err != nil {
+		return err
+	}
+
+	localPaths, err := localPostPaths(opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	listed := NewListedPosts(posts, filter, localPaths)
+	err = SortListedPosts(listed, opts.Sort, opts.Reverse)
+	if err != nil {
+		return err
+	}
+
+	if opts.CSV {
+		return WriteListCSV(output.w, listed)
+	}
+	if output.Format == OutputFormatJSON {
+		for _, post := range listed {
+			// 他のコマンドの結果と同じく、action で見分けられるようにする
+			err = output.Print(struct {
+				Action string `json:"action"`
+				ListedPost
+			}{"listed", post}, "")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return WriteListTable(output.w, listed)
+}
+
+// ファイルのパスなら、そのメタデータから投稿の ID を取り出す
+// ファイルが無ければ、target 自体を ID として扱う
+func resolvePostId(target string) (string, string, error) {
+	if _, err := os.Stat(target); err != nil {
+		if target == "" {
+			return "", "", fmt.Errorf("post id is empty")
+		}
+		return target, "", nil
+	}
+
+	m, _, err := readFile(target)
+	if err != nil {
+		return "", "", err
+	}
+	if m.Id == "" {
+		return "", "", fmt.Errorf("%s has no id", target)
+	}
+	return m.Id, target, nil
+}
+
+// y か yes が入力されたときだけ true を返す
+func confirm(r io.Reader, w io.Writer, message string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N]: ", message)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// ファイルを読み込み、メタデータと本文に分ける
+func readFile(path string) (*meta, string, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer fi.Close()
+	bytes, err := io.ReadAll(fi)
+	if err != nil {
+		return nil, "", err
+	}
+	m, body, _, err := parseFile(path, string(bytes))
+	return m, body, err
+}
+
+// ファイルの内容をメタデータと本文に分け、本文が始まる行番号と一緒に返す
+// path は本文の書式を拡張子から決めるために使う
+func parseFile(path string, content string) (*meta, string, int, error) {
+	m := meta{}
+	body, bodyLine, err := ParseFrontMatter(path, content, &m)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if m.Format == "" {
+		m.Format = BodyFormatFromPath(path)
+	}
+	_, err = LookupBodyFormat(m.Format)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return &m, body, bodyLine, nil
+}
+
+func newEntryFromMeta(m *meta, body string) *Entry {
+	entry := NewEntry(m.Id, m.Title, m.Status, m.Fee, body)
+	entry.Embeds = m.Embeds
+	entry.UpdatedAt = m.UpdatedAt
+	entry.Format = m.Format
+	return entry
+}
+
+// YYYY-MM-DD-ID.md の形で、指定したディレクトリにファイルを保存し、そのパスを返す
+// 更新日時が分からなければ、今日の日付を使う
+func saveFile(dir string, entry Entry) (string, error) {
+	parsedTime := time.Now()
+	if entry.UpdatedAt != "" {
+		var err error
+		parsedTime, err = time.Parse(time.RFC3339, entry.UpdatedAt)
+		if err != nil {
+			return "", err
+		}
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("%s-%s%s", parsedTime.Format(time.DateOnly), entry.ID, BodyFormatFileExt(entry.Format)))
+
+	return filePath, writeFile(filePath, entry)
+}
+
+// メタデータと本文を、指定したパスに書き込む
+func writeFile(filePath string, entry Entry) error {
+	meta := &meta{
+		Id:         entry.ID,
+		Title:      entry.Title,
+		Status:     string(entry.Status),
+		Fee:        string(entry.Fee),
+		Restricted: entry.Restricted,
+		Embeds:     entry.Embeds,
+		UpdatedAt:  entry.UpdatedAt,
+	}
+	// 拡張子から分かる書式なら、front matter には書かない
+	if entry.Format != BodyFormatFromPath(filePath) {
+		meta.Format = entry.Format
+	}
+	// TOML で書かれたファイルは TOML のまま上書きする
+	metaString, err := formatFrontMatter(existingFrontMatterDelimiter(filePath), meta)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filePath, []byte(strings.Join([]string{metaString, entry.Body}, "\n")))
+}
@@ -0,0 +1,722 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/defaultcf/fanboxsync/backup"
+	"github.com/defaultcf/fanboxsync/fanbox"
+	"github.com/defaultcf/fanboxsync/history"
+	"github.com/stretchr/testify/assert"
+)
+
+// コマンドは履歴を $HOME の下に書くので、t.Setenv を使うテストは並列にしない
+func TestCommandCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		pushErr   error
+		wantErr   bool
+		wantFiles []string
+	}{
+		{
+			name:      "投稿を作ってタイトルを送り、更新日時と ID の名前でファイルを作る",
+			wantFiles: []string{"2024-01-01-1000000.md"},
+		},
+		{
+			name:      "タイトルを送れなければ、ファイルは作らずにエラーにする",
+			pushErr:   errors.New("failed to update"),
+			wantErr:   true,
+			wantFiles: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("HOME", t.TempDir())
+			dir := t.TempDir()
+			t.Chdir(dir)
+			SetOutput(NewOutput(OutputFormatText, io.Discard))
+			t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+			posts := map[string]fanboxgo.Post{}
+			client := fanbox.NewFakeFanbox(posts)
+			if tt.pushErr != nil {
+				client.SetError("post.update", tt.pushErr)
+			}
+
+			// execute
+			err := CommandCreate(fanbox.NewTestFanbox(client), "タイトル", false)
+
+			// verify
+			files := []string{}
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+			assert.Equal(t, tt.wantFiles, files)
+			if tt.wantErr {
+				// 作られた投稿を pull できるよう、ID をエラーに含める
+				assert.ErrorContains(t, err, "1000000")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "タイトル", posts["1000000"].Title.Value)
+			content, err := os.ReadFile(filepath.Join(dir, files[0]))
+			assert.NoError(t, err)
+			assert.Contains(t, string(content), "title: タイトル")
+			assert.Contains(t, string(content), "updated_at: \"2024-01-01T00:00:00+09:00\"")
+		})
+	}
+}
+
+func TestCommandCreateFromFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		pushErr   error
+		wantErr   bool
+		wantTitle string
+	}{
+		{
+			name:      "作った投稿の ID を書き戻し、本文を送る",
+			wantTitle: "タイトル",
+		},
+		{
+			name:    "本文を送れなくても ID は書き戻す",
+			pushErr: errors.New("failed to update"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("HOME", t.TempDir())
+			path := filepath.Join(t.TempDir(), "post.md")
+			err := os.WriteFile(path, []byte("---\ntitle: タイトル\n---\n\n本文\n"), 0644)
+			assert.NoError(t, err)
+			posts := map[string]fanboxgo.Post{}
+			client := fanbox.NewFakeFanbox(posts)
+			if tt.pushErr != nil {
+				client.SetError("post.update", tt.pushErr)
+			}
+
+			// execute
+			err = CommandCreateFromFile(fanbox.NewTestFanbox(client), path, false)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, posts, 1)
+			assert.Equal(t, tt.wantTitle, posts["1000000"].Title.Value)
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Contains(t, string(content), `id: "1000000"`)
+
+			// ID が書き戻されているので、やり直しても同じ投稿は作られない
+			err = CommandCreateFromFile(fanbox.NewTestFanbox(client), path, false)
+			assert.Error(t, err)
+			assert.Len(t, posts, 1)
+		})
+	}
+}
+
+func TestCommandDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      fanboxgo.PostStatus
+		byId        bool
+		opts        DeleteOptions
+		trashFiles  []string // ゴミ箱に先にあるファイル
+		wantErr     bool
+		wantDeleted bool
+		wantTrash   []string
+	}{
+		{
+			name:        "y と答えると削除し、ファイルをゴミ箱に移す",
+			status:      fanboxgo.PostStatusDraft,
+			opts:        DeleteOptions{Stdin: strings.NewReader("y\n")},
+			wantDeleted: true,
+			wantTrash:   []string{"post.md"},
+		},
+		{
+			name:      "y 以外を答えると削除しない",
+			status:    fanboxgo.PostStatusDraft,
+			opts:      DeleteOptions{Stdin: strings.NewReader("n\n")},
+			wantTrash: []string{},
+		},
+		{
+			name:      "dry-run では削除も移動もしない",
+			status:    fanboxgo.PostStatusDraft,
+			opts:      DeleteOptions{DryRun: true},
+			wantTrash: []string{},
+		},
+		{
+			name:      "公開済みの投稿は --force が無ければ削除しない",
+			status:    fanboxgo.PostStatusPublished,
+			opts:      DeleteOptions{Yes: true},
+			wantErr:   true,
+			wantTrash: []string{},
+		},
+		{
+			name:        "公開済みの投稿も --force なら削除する",
+			status:      fanboxgo.PostStatusPublished,
+			opts:        DeleteOptions{Yes: true, Force: true},
+			wantDeleted: true,
+			wantTrash:   []string{"post.md"},
+		},
+		{
+			name:        "ID で指定しても手元のファイルを探して移す",
+			status:      fanboxgo.PostStatusDraft,
+			byId:        true,
+			opts:        DeleteOptions{Yes: true},
+			wantDeleted: true,
+			wantTrash:   []string{"post.md"},
+		},
+		{
+			name:        "ゴミ箱に同じ名前があれば上書きせずに番号を付ける",
+			status:      fanboxgo.PostStatusDraft,
+			opts:        DeleteOptions{Yes: true},
+			trashFiles:  []string{"post.md"},
+			wantDeleted: true,
+			wantTrash:   []string{"post-1.md", "post.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("HOME", t.TempDir())
+			dir := t.TempDir()
+			path := filepath.Join(dir, "post.md")
+			err := os.WriteFile(path, []byte("---\nid: \"1000000\"\ntitle: タイトル\n---\n\n本文\n"), 0644)
+			assert.NoError(t, err)
+			trashDir := filepath.Join(t.TempDir(), "trash")
+			for _, name := range tt.trashFiles {
+				assert.NoError(t, os.MkdirAll(trashDir, 0755))
+				assert.NoError(t, os.WriteFile(filepath.Join(trashDir, name), []byte("前に捨てたファイル"), 0644))
+			}
+			posts := map[string]fanboxgo.Post{
+				"1000000": {
+					ID:     fanboxgo.NewOptString("1000000"),
+					Title:  fanboxgo.NewOptString("タイトル"),
+					Status: fanboxgo.NewOptPostStatus(tt.status),
+				},
+			}
+			f := fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts))
+			if tt.opts.DryRun {
+				f = fanbox.NewDryRunFanbox(f, io.Discard)
+			}
+			target := path
+			if tt.byId {
+				target = "1000000"
+			}
+			opts := tt.opts
+			opts.TrashDir = trashDir
+			opts.Dir = dir
+
+			// execute
+			err = CommandDelete(f, target, opts)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			_, exist := posts["1000000"]
+			assert.Equal(t, tt.wantDeleted, !exist)
+			trash := []string{}
+			entries, _ := os.ReadDir(trashDir)
+			for _, entry := range entries {
+				trash = append(trash, entry.Name())
+			}
+			assert.Equal(t, tt.wantTrash, trash)
+			_, err = os.Stat(path)
+			assert.Equal(t, tt.wantDeleted, errors.Is(err, os.ErrNotExist))
+			for _, name := range tt.trashFiles {
+				content, err := os.ReadFile(filepath.Join(trashDir, name))
+				assert.NoError(t, err)
+				assert.Equal(t, "前に捨てたファイル", string(content))
+			}
+		})
+	}
+}
+
+func TestCommandPush(t *testing.T) {
+	tests := []struct {
+		name            string
+		localUpdatedAt  string
+		remoteUpdatedAt string
+		opts            PushOptions
+		noUpdatedAt     bool
+		wantConflict    *ConflictError
+		wantTitle       string
+		wantUpdatedAt   string
+	}{
+		{
+			name:            "pull してから更新されていなければ push し、更新後の日時を書き戻す",
+			localUpdatedAt:  "2024-05-10T12:00:00+09:00",
+			remoteUpdatedAt: "2024-05-10T12:00:00+09:00",
+			wantTitle:       "新しいタイトル",
+			wantUpdatedAt:   "2024-05-10T12:01:00+09:00",
+		},
+		{
+			name:            "pull してから更新されていれば上書きしない",
+			localUpdatedAt:  "2024-05-10T12:00:00+09:00",
+			remoteUpdatedAt: "2024-05-11T12:00:00+09:00",
+			wantConflict: &ConflictError{
+				ID:              "1000000",
+				LocalUpdatedAt:  "2024-05-10T12:00:00+09:00",
+				RemoteUpdatedAt: "2024-05-11T12:00:00+09:00",
+			},
+			wantTitle:     "サーバー上のタイトル",
+			wantUpdatedAt: "2024-05-10T12:00:00+09:00",
+		},
+		{
+			name:            "--force なら更新されていても上書きする",
+			localUpdatedAt:  "2024-05-10T12:00:00+09:00",
+			remoteUpdatedAt: "2024-05-11T12:00:00+09:00",
+			opts:            PushOptions{Force: true},
+			wantTitle:       "新しいタイトル",
+			wantUpdatedAt:   "2024-05-11T12:01:00+09:00",
+		},
+		{
+			name:            "updated_at が無ければ確かめずに push する",
+			remoteUpdatedAt: "2024-05-11T12:00:00+09:00",
+			wantTitle:       "新しいタイトル",
+			wantUpdatedAt:   "2024-05-11T12:01:00+09:00",
+		},
+		{
+			name:            "更新の結果に日時が無ければ、取得し直した日時を書き戻す",
+			localUpdatedAt:  "2024-05-10T12:00:00+09:00",
+			remoteUpdatedAt: "2024-05-10T12:00:00+09:00",
+			noUpdatedAt:     true,
+			wantTitle:       "新しいタイトル",
+			wantUpdatedAt:   "2024-05-10T12:01:00+09:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("HOME", t.TempDir())
+			path := filepath.Join(t.TempDir(), "post.md")
+			content := "---\nid: \"1000000\"\ntitle: 新しいタイトル\nstatus: draft\nfee: \"0\"\n"
+			if tt.localUpdatedAt != "" {
+				content += "updated_at: \"" + tt.localUpdatedAt + "\"\n"
+			}
+			err := os.WriteFile(path, []byte(content+"---\n\n本文\n"), 0644)
+			assert.NoError(t, err)
+			posts := map[string]fanboxgo.Post{
+				"1000000": {
+					ID:          fanboxgo.NewOptString("1000000"),
+					Title:       fanboxgo.NewOptString("サーバー上のタイトル"),
+					Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+					FeeRequired: fanboxgo.NewOptInt(0),
+					UpdatedAt:   fanboxgo.NewOptString(tt.remoteUpdatedAt),
+				},
+			}
+
+			fake := fanbox.NewFakeFanbox(posts)
+			if tt.noUpdatedAt {
+				fake.SetNoUpdatedAt("1000000")
+			}
+
+			// execute
+			err = CommandPush(fanbox.NewTestFanbox(fake), path, tt.opts)
+
+			// verify
+			if tt.wantConflict != nil {
+				var conflictErr *ConflictError
+				assert.ErrorAs(t, err, &conflictErr)
+				assert.Equal(t, tt.wantConflict, conflictErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantTitle, posts["1000000"].Title.Value)
+			written, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Contains(t, string(written), tt.wantUpdatedAt)
+		})
+	}
+}
+
+func TestCommandRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       RollbackOptions
+		wantStatus fanboxgo.PostStatus
+	}{
+		{
+			name:       "本文だけを戻し、ステータスは今のままにする",
+			wantStatus: fanboxgo.PostStatusPublished,
+		},
+		{
+			name:       "--restore-status ならステータスも戻す",
+			opts:       RollbackOptions{RestoreStatus: true},
+			wantStatus: fanboxgo.PostStatusDraft,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			store := history.NewStore(filepath.Join(home, ".local", "share", "fanboxsync", "history"))
+			revision, err := store.Save(fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("古いタイトル"),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				FeeRequired: fanboxgo.NewOptInt(0),
+			})
+			assert.NoError(t, err)
+			posts := map[string]fanboxgo.Post{
+				"1000000": {
+					ID:          fanboxgo.NewOptString("1000000"),
+					Title:       fanboxgo.NewOptString("新しいタイトル"),
+					Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+					FeeRequired: fanboxgo.NewOptInt(0),
+				},
+			}
+			client := fanbox.NewFakeFanbox(posts)
+			client.SetRawPost("1000000", fanbox.RawPost{Type: fanbox.PostTypeArticle, Tags: []string{"日記"}})
+			f := fanbox.NewTestFanbox(client)
+
+			// execute
+			err = CommandRollback(f, "1000000", revision, tt.opts)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, "古いタイトル", posts["1000000"].Title.Value)
+			assert.Equal(t, tt.wantStatus, posts["1000000"].Status.Value)
+			raw, err := f.GetRawPost("1000000")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"日記"}, raw.Tags)
+		})
+	}
+}
+
+func TestCommandPushOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+		want   string
+	}{
+		{
+			name: "push した投稿を 1 行の JSON で書き出す",
+			want: `{"action":"pushed","id":"1000000","path":%q,"title":"タイトル","status":"draft"}`,
+		},
+		{
+			name:   "dry-run でも書き出し、dry_run を付ける",
+			dryRun: true,
+			want:   `{"action":"pushed","id":"1000000","path":%q,"title":"タイトル","status":"draft","dry_run":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("HOME", t.TempDir())
+			var out bytes.Buffer
+			SetOutput(NewOutput(OutputFormatJSON, &out))
+			t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+			path := filepath.Join(t.TempDir(), "post.md")
+			err := os.WriteFile(path, []byte("---\nid: \"1000000\"\ntitle: タイトル\nstatus: draft\nfee: \"0\"\n---\n\n本文\n"), 0644)
+			assert.NoError(t, err)
+			posts := map[string]fanboxgo.Post{
+				"1000000": {ID: fanboxgo.NewOptString("1000000")},
+			}
+			f := fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts))
+			if tt.dryRun {
+				f = fanbox.NewDryRunFanbox(f, io.Discard)
+			}
+
+			// execute
+			err = CommandPush(f, path, PushOptions{DryRun: tt.dryRun})
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(tt.want, path)+"\n", out.String())
+		})
+	}
+}
+
+func TestCommandList(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "JSON の行にも action を付ける",
+			opts: ListOptions{Sort: ListSortID},
+			want: `{"action":"listed","id":"1000000","title":"タイトル","status":"draft","fee":0,"published_at":"","updated_at":""}` + "\n",
+		},
+		{
+			name:    "--csv と --output json は一緒に使えない",
+			opts:    ListOptions{Sort: ListSortID, CSV: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			var out bytes.Buffer
+			SetOutput(NewOutput(OutputFormatJSON, &out))
+			t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+			posts := map[string]fanboxgo.Post{
+				"1000000": {
+					ID:     fanboxgo.NewOptString("1000000"),
+					Title:  fanboxgo.NewOptString("タイトル"),
+					Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				},
+			}
+			filter, err := NewPullFilter("", "", "", "", "", "")
+			assert.NoError(t, err)
+			opts := tt.opts
+			opts.Dir = t.TempDir()
+
+			// execute
+			err = CommandList(fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts)), filter, opts)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, out.String())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestCommandArchive(t *testing.T) {
+	tests := []struct {
+		name      string
+		creatorId string
+		failures  []string // 本文の取得に失敗する投稿
+		wantErr   bool
+		wantFiles []string
+	}{
+		{
+			name:      "投稿をクリエイターの ID のディレクトリに保存する",
+			creatorId: "fanbox",
+			wantFiles: []string{"2024-05-10-1000000.md", "2024-05-11-1000001.md"},
+		},
+		{
+			name:      "取得できなかった投稿は飛ばして、残りを保存する",
+			creatorId: "fanbox",
+			failures:  []string{"1000000"},
+			wantFiles: []string{"2024-05-11-1000001.md"},
+		},
+		{
+			name:      "ディレクトリの外を指す ID は受け付けない",
+			creatorId: "../fanbox",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			dir := t.TempDir()
+			t.Chdir(dir)
+			SetOutput(NewOutput(OutputFormatText, io.Discard))
+			t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+			body := fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+				Blocks: []fanboxgo.PostBodyBlocksItem{
+					{
+						Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+						Text: fanboxgo.NewOptString("本文"),
+					},
+				},
+			})
+			client := fanbox.NewFakePublicHttpClient("fanbox", map[string]fanbox.PublicPost{
+				"1000000": {Post: fanboxgo.Post{ID: fanboxgo.NewOptString("1000000"), UpdatedAt: fanboxgo.NewOptString("2024-05-10T12:00:00+09:00"), Body: body}},
+				"1000001": {Post: fanboxgo.Post{ID: fanboxgo.NewOptString("1000001"), UpdatedAt: fanboxgo.NewOptString("2024-05-11T12:00:00+09:00"), Body: body}},
+			})
+			for _, postId := range tt.failures {
+				client.SetInfoError(postId)
+			}
+
+			// execute
+			err := CommandArchive(fanbox.NewPublicFanbox(client, "", ""), tt.creatorId, nil)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			files := []string{}
+			entries, err := os.ReadDir(filepath.Join(dir, tt.creatorId))
+			assert.NoError(t, err)
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+			assert.Equal(t, tt.wantFiles, files)
+		})
+	}
+}
+
+func TestCommandPushEmbeds(t *testing.T) {
+	// setup
+	t.Setenv("HOME", t.TempDir())
+	SetOutput(NewOutput(OutputFormatText, io.Discard))
+	t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+	path := filepath.Join(t.TempDir(), "post.md")
+	content := "---\nid: \"1000000\"\ntitle: タイトル\nstatus: draft\nfee: \"0\"\nembeds:\n  abc: https://example.com/\n---\n\n[abc](https://example.com/)\n[前回](https://creator.fanbox.cc/posts/123)\n"
+	err := os.WriteFile(path, []byte(content), 0644)
+	assert.NoError(t, err)
+	card := fanboxgo.PostBodyUrlEmbedMapItem{
+		ID:   fanboxgo.NewOptString("abc"),
+		Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTMLCard),
+		HTML: fanboxgo.NewOptString(`<a href="https://example.com/">example</a>`),
+	}
+	posts := map[string]fanboxgo.Post{
+		"1000000": {
+			ID:     fanboxgo.NewOptString("1000000"),
+			Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+			Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+				UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{"abc": card}),
+			}),
+		},
+	}
+
+	// execute
+	err = CommandPush(fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts)), path, PushOptions{})
+
+	// verify
+	assert.NoError(t, err)
+	// FANBOX 上の埋め込みは種類を変えずに残り、投稿へのリンクは fanbox.post の埋め込みになる
+	urlEmbedMap := posts["1000000"].Body.Value.UrlEmbedMap.Value
+	assert.Equal(t, card, urlEmbedMap["abc"])
+	assert.Equal(t, fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost, urlEmbedMap["fanbox-post-123"].Type.Value)
+	assert.Equal(t, "123", urlEmbedMap["fanbox-post-123"].PostInfo.Value.ID.Value)
+	assert.Equal(t, "creator", urlEmbedMap["fanbox-post-123"].PostInfo.Value.CreatorId.Value)
+}
+
+func TestCommandRestore(t *testing.T) {
+	// setup
+	t.Setenv("HOME", t.TempDir())
+	SetOutput(NewOutput(OutputFormatText, io.Discard))
+	t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+	text := func(v string) fanboxgo.PostBodyBlocksItem {
+		return fanboxgo.PostBodyBlocksItem{
+			Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+			Text: fanboxgo.NewOptString(v),
+		}
+	}
+	embed := func(id string) fanboxgo.PostBodyBlocksItem {
+		return fanboxgo.PostBodyBlocksItem{
+			Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+			UrlEmbedId: fanboxgo.NewOptString(id),
+		}
+	}
+	postEmbed := fanboxgo.PostBodyUrlEmbedMapItem{
+		ID:   fanboxgo.NewOptString("post"),
+		Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost),
+		PostInfo: fanboxgo.NewOptPostBodyUrlEmbedMapItemPostInfo(fanboxgo.PostBodyUrlEmbedMapItemPostInfo{
+			ID:        fanboxgo.NewOptString("123"),
+			CreatorId: fanboxgo.NewOptString("creator"),
+		}),
+	}
+	archivePath := filepath.Join(t.TempDir(), "backup.tar.gz")
+	out, err := os.Create(archivePath)
+	assert.NoError(t, err)
+	w := backup.NewWriter(out)
+	assert.NoError(t, w.AddPost(fanboxgo.Post{
+		ID:     fanboxgo.NewOptString("900"),
+		Title:  fanboxgo.NewOptString("元の投稿"),
+		Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+		Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+			Blocks: []fanboxgo.PostBodyBlocksItem{
+				text("本文"),
+				{
+					Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
+					ImageId: fanboxgo.NewOptString("img"),
+				},
+				embed("card"),
+				embed("post"),
+				embed("missing"),
+			},
+			UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{
+				"card": {
+					ID:   fanboxgo.NewOptString("card"),
+					Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTMLCard),
+					HTML: fanboxgo.NewOptString(`<a href="https://example.com/">example</a>`),
+				},
+				"post": postEmbed,
+			}),
+		}),
+	}))
+	assert.NoError(t, w.AddMeta("900", backup.Meta{Tags: []string{"イラスト"}}))
+	assert.NoError(t, w.Close())
+	assert.NoError(t, out.Close())
+	posts := map[string]fanboxgo.Post{}
+	f := fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts))
+
+	// execute
+	err = CommandRestore(f, archivePath, false)
+
+	// verify
+	assert.NoError(t, err)
+	restored := posts["1000000"]
+	assert.Equal(t, fanboxgo.PostStatusDraft, restored.Status.Value)
+	// 画像と、アーカイブに無い埋め込みは除き、FANBOX の投稿以外の埋め込みはリンク先の段落にする
+	assert.Equal(t, []fanboxgo.PostBodyBlocksItem{text("本文"), text("https://example.com/"), embed("post")}, restored.Body.Value.Blocks)
+	assert.Equal(t, fanboxgo.PostBodyUrlEmbedMap{"post": postEmbed}, restored.Body.Value.UrlEmbedMap.Value)
+	raw, err := f.GetRawPost("1000000")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"イラスト"}, raw.Tags)
+}
+
+func TestCommandPushImages(t *testing.T) {
+	// setup
+	t.Setenv("HOME", t.TempDir())
+	SetOutput(NewOutput(OutputFormatText, io.Discard))
+	t.Cleanup(func() { SetOutput(NewOutput(OutputFormatText, os.Stdout)) })
+	path := filepath.Join(t.TempDir(), "post.md")
+	content := "---\nid: \"1000000\"\ntitle: タイトル\nstatus: draft\nfee: \"0\"\n---\n\n![img](https://downloads.fanbox.cc/images/post/1000000/img.png)\n![a.png](https://example.com/a.png)"
+	err := os.WriteFile(path, []byte(content), 0644)
+	assert.NoError(t, err)
+	posts := map[string]fanboxgo.Post{
+		"1000000": {
+			ID:     fanboxgo.NewOptString("1000000"),
+			Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+			Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+				ImageMap: fanboxgo.NewOptPostBodyImageMap(fanboxgo.PostBodyImageMap{
+					"img": {ID: fanboxgo.NewOptString("img")},
+				}),
+			}),
+		},
+	}
+
+	// execute
+	err = CommandPush(fanbox.NewTestFanbox(fanbox.NewFakeFanbox(posts)), path, PushOptions{})
+
+	// verify
+	assert.NoError(t, err)
+	// アップロードされていない画像は、代替テキストを ID として送らずに除く
+	assert.Equal(t, []fanboxgo.PostBodyBlocksItem{
+		{
+			Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
+			ImageId: fanboxgo.NewOptString("img"),
+		},
+	}, posts["1000000"].Body.Value.Blocks)
+}
@@ -0,0 +1,54 @@
+package main_test
+
+import (
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "同じなら全ての行が共通になる",
+			before: "1行目\n2行目",
+			after:  "1行目\n2行目",
+			want:   " 1行目\n 2行目",
+		},
+		{
+			name:   "書き換えた行は削除と追加になる",
+			before: "1行目\n2行目\n3行目",
+			after:  "1行目\n書き換えた2行目\n3行目",
+			want:   " 1行目\n-2行目\n+書き換えた2行目\n 3行目",
+		},
+		{
+			name:   "末尾の追加と削除",
+			before: "1行目\n2行目",
+			after:  "2行目\n3行目",
+			want:   "-1行目\n 2行目\n+3行目",
+		},
+		{
+			name:   "空の本文から書いた",
+			before: "",
+			after:  "1行目",
+			want:   "-\n+1行目",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			diff := DiffLines(tt.before, tt.after)
+
+			// verify
+			assert.Equal(t, tt.want, diff)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	"golang.org/x/net/html"
+)
+
+// 埋め込みから URL を取り出せなかったときのエラー
+type EmbedError struct {
+	ID     string
+	Type   fanboxgo.PostBodyUrlEmbedMapItemType
+	Reason string
+}
+
+func (e *EmbedError) Error() string {
+	return fmt.Sprintf("failed to parse embed %s (%s): %s", e.ID, e.Type, e.Reason)
+}
+
+// 埋め込みの HTML から、埋め込まれている URL を取り出す
+// iframe があればその src を、無ければ a の href を使う
+func findEmbedSource(rawHtml string) (string, error) {
+	node, err := html.Parse(strings.NewReader(rawHtml))
+	if err != nil {
+		return "", err
+	}
+
+	if iframe := findElement(node, "iframe", "src"); iframe != "" {
+		return iframe, nil
+	}
+	// Twitter/X は blockquote の最後のリンクが投稿自体を指す
+	if quote := findTwitterQuote(node); quote != nil {
+		if href := findLastElement(quote, "a", "href"); href != "" {
+			return href, nil
+		}
+	}
+	if href := findElement(node, "a", "href"); href != "" {
+		return href, nil
+	}
+
+	return "", fmt.Errorf("no iframe or anchor found")
+}
+
+func findElement(node *html.Node, tag string, key string) string {
+	if node.Type == html.ElementNode && node.Data == tag {
+		if val := getAttr(node, key); val != "" {
+			return val
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if val := findElement(child, tag, key); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+func findLastElement(node *html.Node, tag string, key string) string {
+	for child := node.LastChild; child != nil; child = child.PrevSibling {
+		if val := findLastElement(child, tag, key); val != "" {
+			return val
+		}
+	}
+	if node.Type == html.ElementNode && node.Data == tag {
+		return getAttr(node, key)
+	}
+	return ""
+}
+
+func findTwitterQuote(node *html.Node) *html.Node {
+	if node.Type == html.ElementNode && node.Data == "blockquote" && strings.Contains(getAttr(node, "class"), "twitter-tweet") {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findTwitterQuote(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func getAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+var (
+	reYoutubeEmbed = regexp.MustCompile(`^/embed/([\w-]+)`)
+	reVimeoEmbed   = regexp.MustCompile(`^/video/(\d+)`)
+	reSpotifyEmbed = regexp.MustCompile(`^/embed(/.+)`)
+
+	reFanboxPostUrl = regexp.MustCompile(`^https://(?:([\w-]+)\.fanbox\.cc|www\.fanbox\.cc/@([\w-]+))/posts/(\d+)$`)
+)
+
+// 埋め込み用の URL を、ブラウザで開く URL に戻す
+func normalizeEmbedUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	switch u.Host {
+	case "www.youtube.com", "youtube.com", "www.youtube-nocookie.com":
+		if matches := reYoutubeEmbed.FindStringSubmatch(u.Path); len(matches) > 0 {
+			return fmt.Sprintf("https://www.youtube.com/watch?v=%s", matches[1])
+		}
+	case "player.vimeo.com":
+		if matches := reVimeoEmbed.FindStringSubmatch(u.Path); len(matches) > 0 {
+			return fmt.Sprintf("https://vimeo.com/%s", matches[1])
+		}
+	case "open.spotify.com":
+		if matches := reSpotifyEmbed.FindStringSubmatch(u.Path); len(matches) > 0 {
+			return fmt.Sprintf("https://open.spotify.com%s", matches[1])
+		}
+	case "embed.pixiv.net":
+		id := u.Query().Get("illust_id")
+		if id == "" {
+			id = u.Query().Get("id")
+		}
+		if id != "" {
+			return fmt.Sprintf("https://www.pixiv.net/artworks/%s", id)
+		}
+	case "twitter.com", "x.com", "mobile.twitter.com":
+		// 埋め込みコードに付く ?ref_src=... などを除く
+		u.RawQuery = ""
+		return u.String()
+	}
+
+	return rawUrl
+}
+
+// FANBOX の投稿の URL なら、その投稿を指す fanbox.post の埋め込みを作る
+// 同じ投稿へのリンクが同じ埋め込みになるよう、ID はリンク先の投稿の ID から決める
+func newFanboxPostEmbed(rawUrl string) (fanboxgo.PostBodyUrlEmbedMapItem, bool) {
+	matches := reFanboxPostUrl.FindStringSubmatch(rawUrl)
+	if len(matches) == 0 || matches[1] == "www" {
+		return fanboxgo.PostBodyUrlEmbedMapItem{}, false
+	}
+	creatorId := matches[1]
+	if creatorId == "" {
+		creatorId = matches[2]
+	}
+
+	embedId := "fanbox-post-" + matches[3]
+	return fanboxgo.PostBodyUrlEmbedMapItem{
+		ID:   fanboxgo.NewOptString(embedId),
+		Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost),
+		PostInfo: fanboxgo.NewOptPostBodyUrlEmbedMapItemPostInfo(fanboxgo.PostBodyUrlEmbedMapItemPostInfo{
+			ID:        fanboxgo.NewOptString(matches[3]),
+			CreatorId: fanboxgo.NewOptString(creatorId),
+		}),
+	}, true
+}
@@ -0,0 +1,117 @@
+package main_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertPostEmbed(t *testing.T) {
+	tests := []struct {
+		name    string
+		embed   fanboxgo.PostBodyUrlEmbedMapItem
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "YouTube の埋め込みを URL に戻せる",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<html><head></head><body><div><iframe src="https://www.youtube.com/embed/abcDEF123_-?feature=oembed"></iframe></div></body></html>`),
+			},
+			want: "[embed](https://www.youtube.com/watch?v=abcDEF123_-)",
+		},
+		{
+			name: "Vimeo の埋め込みを URL に戻せる",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<iframe src="https://player.vimeo.com/video/123456"></iframe>`),
+			},
+			want: "[embed](https://vimeo.com/123456)",
+		},
+		{
+			name: "Spotify の埋め込みを URL に戻せる",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<iframe src="https://open.spotify.com/embed/track/xyz"></iframe>`),
+			},
+			want: "[embed](https://open.spotify.com/track/xyz)",
+		},
+		{
+			name: "pixiv の埋め込みを URL に戻せる",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<iframe src="https://embed.pixiv.net/embed_mk2.php?id=98765&size=medium"></iframe>`),
+			},
+			want: "[embed](https://www.pixiv.net/artworks/98765)",
+		},
+		{
+			name: "Twitter の埋め込みは投稿自体のリンクを使う",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<blockquote class="twitter-tweet"><p>見て <a href="https://t.co/abc">pic</a></p>&mdash; user (@user) <a href="https://twitter.com/user/status/123?ref_src=twsrc%5Etfw">2024</a></blockquote>`),
+			},
+			want: "[embed](https://twitter.com/user/status/123)",
+		},
+		{
+			name: "Booth のリンクはそのまま使う",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<div><a href="https://booth.pm/ja/items/111">Booth</a></div>`),
+			},
+			want: "[embed](https://booth.pm/ja/items/111)",
+		},
+		{
+			name: "iframely のクライアントが無ければ iframely の URL のまま残す",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTMLCard),
+				HTML: fanboxgo.NewOptString(`<div><iframe src="https://cdn.iframe.ly/abc123"></iframe></div>`),
+			},
+			want: "[embed](https://cdn.iframe.ly/abc123)",
+		},
+		{
+			name: "URL が見つからなければエラーが返る",
+			embed: fanboxgo.PostBodyUrlEmbedMapItem{
+				Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeHTML),
+				HTML: fanboxgo.NewOptString(`<div>empty</div>`),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			e := Entry{}
+			post := fanboxgo.Post{
+				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+					Blocks: []fanboxgo.PostBodyBlocksItem{
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("embed"),
+						},
+					},
+					UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{
+						"embed": tt.embed,
+					}),
+				}),
+			}
+
+			// execute
+			converted, err := e.ConvertPost(&post)
+
+			// verify
+			if tt.wantErr {
+				var embedErr *EmbedError
+				assert.ErrorAs(t, err, &embedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, converted.Body)
+		})
+	}
+}
@@ -1,18 +1,16 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"regexp"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	fanboxgo "github.com/defaultcf/fanbox-go"
 	"github.com/defaultcf/fanboxsync/iframely"
-	"golang.org/x/net/html"
 )
 
 type Entry struct {
@@ -24,6 +22,10 @@ type Entry struct {
 	Body           string
 	UpdatedAt      string
 	PublishedAt    string
+	Restricted     bool              // 閲覧権限が無く、本文が取得できなかった
+	Embeds         map[string]string // 埋め込みの ID と、pull した時点の URL
+	RemoteEmbeds   map[string]bool   // push する時点で FANBOX 上にある埋め込みの ID。nil なら Embeds から決める
+	Format         string            // 本文の書式。空なら markdown として扱う
 }
 
 func NewEntry(id string, title string, status string, fee string, body string) *Entry {
@@ -37,30 +39,130 @@ func NewEntry(id string, title string, status string, fee string, body string) *
 	}
 }
 
+// 本文の書式
+// ファイルごとに、front matter の format か拡張子で選ぶ
+type BodyFormat interface {
+	// entry の本文を Fanbox の本文にする
+	Parse(entry *Entry) (*fanboxgo.PostBody, error)
+	// Fanbox の本文をこの書式の本文にし、埋め込みの ID と URL の対応と一緒に返す
+	Render(e *Entry, post *fanboxgo.Post) (string, map[string]string, error)
+}
+
+const BodyFormatMarkdown = "markdown"
+
+var (
+	bodyFormats       = map[string]BodyFormat{}
+	bodyFormatExts    = map[string]string{}
+	bodyFormatFileExt = map[string]string{} // 書式ごとに、新しく作るファイルの拡張子
+)
+
+func init() {
+	RegisterBodyFormat(BodyFormatMarkdown, markdownFormat{}, ".md", ".markdown")
+}
+
+// 本文の書式を登録する
+// exts に拡張子を渡すと、front matter に format が無いファイルでもその書式を使う
+// 新しく作るファイルには、最初の拡張子を使う
+func RegisterBodyFormat(name string, format BodyFormat, exts ...string) {
+	bodyFormats[name] = format
+	if len(exts) > 0 {
+		bodyFormatFileExt[name] = exts[0]
+	}
+	for _, ext := range exts {
+		bodyFormatExts[ext] = name
+	}
+}
+
+func LookupBodyFormat(name string) (BodyFormat, error) {
+	if name == "" {
+		name = BodyFormatMarkdown
+	}
+	format, exist := bodyFormats[name]
+	if !exist {
+		return nil, fmt.Errorf("unknown body format: %s", name)
+	}
+	return format, nil
+}
+
+// 拡張子から本文の書式を決める
+// 登録されていない拡張子なら markdown として扱う
+func BodyFormatFromPath(path string) string {
+	if name, exist := bodyFormatExts[strings.ToLower(filepath.Ext(path))]; exist {
+		return name
+	}
+	return BodyFormatMarkdown
+}
+
+// 書式の本文を保存するファイルの拡張子を返す
+// 拡張子を登録していない書式は、front matter の format で見分けるので .md にする
+func BodyFormatFileExt(name string) string {
+	if ext, exist := bodyFormatFileExt[name]; exist {
+		return ext
+	}
+	return ".md"
+}
+
+// Fanbox から Entry の書式に変換する
+func (e *Entry) ConvertPost(post *fanboxgo.Post) (*Entry, error) {
+	format, err := LookupBodyFormat(e.Format)
+	if err != nil {
+		return nil, err
+	}
+	body, embeds, err := format.Render(e, post)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		ID:          post.ID.Value,
+		Title:       post.Title.Value,
+		Status:      post.Status.Value,
+		Fee:         fmt.Sprint(post.FeeRequired.Value),
+		Body:        body,
+		UpdatedAt:   post.UpdatedAt.Value,
+		PublishedAt: post.PublishedAt.Value,
+		Embeds:      embeds,
+		Format:      e.Format,
+	}, nil
+}
+
+func (e *Entry) ConvertFanbox(entry *Entry) (*fanboxgo.Post, error) {
+	format, err := LookupBodyFormat(entry.Format)
+	if err != nil {
+		return nil, err
+	}
+	postBody, err := format.Parse(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := strconv.Atoi(entry.Fee)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fanboxgo.Post{
+		ID:          fanboxgo.NewOptString(entry.ID),
+		Title:       fanboxgo.NewOptString(entry.Title),
+		Status:      fanboxgo.NewOptPostStatus(entry.Status),
+		FeeRequired: fanboxgo.NewOptInt(fee),
+		Body:        fanboxgo.NewOptPostBody(*postBody),
+	}, nil
+}
+
+type markdownFormat struct{}
+
 // Fanbox から Markdown の形式に変換する
-func (e *Entry) ConvertPost(post *fanboxgo.Post) *Entry {
+func (markdownFormat) Render(e *Entry, post *fanboxgo.Post) (string, map[string]string, error) {
 	var body []string
+	var embeds map[string]string
 	for _, block := range post.Body.Value.Blocks {
-		runeText := []rune(block.Text.Value)
-		processedText := ""
-		strPointer := 0
 		switch t, _ := block.Type.Get(); t {
 		case fanboxgo.PostBodyBlocksItemTypeP:
-			// offset で昇順ソート
-			sort.SliceStable(block.Styles, func(i, j int) bool { return block.Styles[i].Offset.Value < block.Styles[j].Offset.Value })
-			for _, style := range block.Styles {
-				switch style.Type.Value {
-				case fanboxgo.PostBodyBlocksItemStylesItemTypeBold: // 現在のところ bold だけ確認されている
-					processedText += string(runeText[strPointer:style.Offset.Value])
-					nextPointer := style.Offset.Value + style.Length.Value
-					processedText += fmt.Sprintf("**%s**", string(runeText[style.Offset.Value:nextPointer]))
-					strPointer = nextPointer
-				default:
-					log.Fatal("unknown style type")
-				}
+			processedText, err := renderStyledText(block, "**", "**", nil)
+			if err != nil {
+				return "", nil, err
 			}
-			// 残りの部分を追加
-			processedText += string(runeText[strPointer:])
 			body = append(body, processedText)
 		case fanboxgo.PostBodyBlocksItemTypeHeader:
 			body = append(body, fmt.Sprintf("## %s", block.Text.Value))
@@ -70,30 +172,56 @@ func (e *Entry) ConvertPost(post *fanboxgo.Post) *Entry {
 			urlType := post.Body.Value.UrlEmbedMap.Value[block.UrlEmbedId.Value].Type.Value
 			url, err := e.getEmbedUrl(urlType, post.Body.Value.UrlEmbedMap.Value[block.UrlEmbedId.Value])
 			if err != nil {
-				log.Fatal(err)
-			} else {
-				body = append(body, fmt.Sprintf("[%s](%s)", block.UrlEmbedId.Value, url))
+				return "", nil, err
+			}
+			body = append(body, fmt.Sprintf("[%s](%s)", block.UrlEmbedId.Value, url))
+			if embeds == nil {
+				embeds = map[string]string{}
 			}
+			embeds[block.UrlEmbedId.Value] = url
 		}
 	}
 
-	return &Entry{
-		ID:          post.ID.Value,
-		Title:       post.Title.Value,
-		Status:      post.Status.Value,
-		Fee:         fmt.Sprint(post.FeeRequired.Value),
-		Body:        strings.Join(body, "\n"),
-		UpdatedAt:   post.UpdatedAt.Value,
-		PublishedAt: post.PublishedAt.Value,
+	return strings.Join(body, "\n"), embeds, nil
+}
+
+// 段落の太字の範囲を open と close で囲む
+// escape があれば、囲む文字以外の部分に使う
+func renderStyledText(block fanboxgo.PostBodyBlocksItem, open string, close string, escape func(string) string) (string, error) {
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	runeText := []rune(block.Text.Value)
+	processedText := ""
+	strPointer := 0
+	// offset で昇順ソート
+	sort.SliceStable(block.Styles, func(i, j int) bool { return block.Styles[i].Offset.Value < block.Styles[j].Offset.Value })
+	for _, style := range block.Styles {
+		switch style.Type.Value {
+		case fanboxgo.PostBodyBlocksItemStylesItemTypeBold: // 現在のところ bold だけ確認されている
+			processedText += escape(string(runeText[strPointer:style.Offset.Value]))
+			nextPointer := style.Offset.Value + style.Length.Value
+			processedText += open + escape(string(runeText[style.Offset.Value:nextPointer])) + close
+			strPointer = nextPointer
+		default:
+			return "", fmt.Errorf("unknown style type: %s", style.Type.Value)
+		}
 	}
+	// 残りの部分を追加
+	processedText += escape(string(runeText[strPointer:]))
+	return processedText, nil
 }
 
-func (e *Entry) ConvertFanbox(entry *Entry) *fanboxgo.Post {
+// Markdown から Fanbox の形式に変換する
+// FANBOX の投稿へのリンクは、新しく作る fanbox.post の埋め込みとして urlEmbedMap にも入れる
+// 画像は送られないが、確かめられるよう書いた場所を imageMap の OriginalUrl に入れる
+func (markdownFormat) Parse(entry *Entry) (*fanboxgo.PostBody, error) {
 	blocks := []fanboxgo.PostBodyBlocksItem{}
+	imageMap := fanboxgo.PostBodyImageMap{}
+	urlEmbedMap := fanboxgo.PostBodyUrlEmbedMap{}
 	for _, v := range strings.Split(entry.Body, "\n") {
 		// Header
-		re := regexp.MustCompile(`^## (.+)`)
-		matches := re.FindStringSubmatch(v)
+		matches := reMarkdownHeader.FindStringSubmatch(v)
 		if len(matches) > 0 {
 			blocks = append(blocks, fanboxgo.PostBodyBlocksItem{
 				Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeHeader),
@@ -102,35 +230,49 @@ func (e *Entry) ConvertFanbox(entry *Entry) *fanboxgo.Post {
 			continue
 		}
 		// Image
-		re = regexp.MustCompile(`^!\[(.+)\]\((.+)\)`)
-		matches = re.FindStringSubmatch(v)
+		matches = reMarkdownImage.FindStringSubmatch(v)
 		if len(matches) > 0 {
 			blocks = append(blocks, fanboxgo.PostBodyBlocksItem{
 				Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
 				ImageId: fanboxgo.NewOptString(matches[1]),
 			})
+			imageMap[matches[1]] = fanboxgo.PostBodyImageMapItem{
+				ID:          fanboxgo.NewOptString(matches[1]),
+				OriginalUrl: fanboxgo.NewOptString(matches[2]),
+			}
 			continue
 		}
 		// UrlEmbed
-		re = regexp.MustCompile(`^\[(.+)\]\((.+)\)`)
-		matches = re.FindStringSubmatch(v)
+		matches = reMarkdownLink.FindStringSubmatch(v)
 		if len(matches) > 0 {
-			blocks = append(blocks, fanboxgo.PostBodyBlocksItem{
-				Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
-				UrlEmbedId: fanboxgo.NewOptString(matches[1]),
-			})
-			continue
+			if entry.hasEmbed(matches[1], matches[2]) {
+				// 埋め込みの中身は FANBOX 上にあるものをそのまま使うので、ID だけを送る
+				blocks = append(blocks, fanboxgo.PostBodyBlocksItem{
+					Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+					UrlEmbedId: fanboxgo.NewOptString(matches[1]),
+				})
+				continue
+			}
+			if embed, ok := newFanboxPostEmbed(matches[2]); ok {
+				blocks = append(blocks, fanboxgo.PostBodyBlocksItem{
+					Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+					UrlEmbedId: embed.ID,
+				})
+				urlEmbedMap[embed.ID.Value] = embed
+				continue
+			}
+			// fanbox-go には URL を埋め込みとして登録する API が無いため、書いた行をそのまま段落として送る
+			slog.Warn("url embed cannot be created through the API, pushed as text", "line", v)
 		}
 		// p
-		re = regexp.MustCompile(`\*\*(.+?)\*\*`)
-		matchIndexes := re.FindAllStringIndex(v, -1) // ここで得られる位置は rune ではなく string のもの
+		matchIndexes := reMarkdownBold.FindAllStringIndex(v, -1) // ここで得られる位置は rune ではなく string のもの
 		styles := []fanboxgo.PostBodyBlocksItemStylesItem{}
 		processedText := ""
 		strPointer := 0
 		for _, matchIndex := range matchIndexes {
 			processedText += v[strPointer:matchIndex[0]]
 			offset := len([]rune(processedText))
-			matchStr := re.FindStringSubmatch(v[matchIndex[0]:matchIndex[1]])[1]
+			matchStr := reMarkdownBold.FindStringSubmatch(v[matchIndex[0]:matchIndex[1]])[1]
 			processedText += matchStr
 			length := len([]rune(processedText)) - offset
 
@@ -159,44 +301,57 @@ func (e *Entry) ConvertFanbox(entry *Entry) *fanboxgo.Post {
 		}
 	}
 
-	fee, err := strconv.Atoi(entry.Fee)
-	if err != nil {
-		return nil
+	postBody := &fanboxgo.PostBody{
+		Blocks: blocks,
+	}
+	if len(imageMap) > 0 {
+		postBody.ImageMap = fanboxgo.NewOptPostBodyImageMap(imageMap)
 	}
+	if len(urlEmbedMap) > 0 {
+		postBody.UrlEmbedMap = fanboxgo.NewOptPostBodyUrlEmbedMap(urlEmbedMap)
+	}
+	return postBody, nil
+}
 
-	return &fanboxgo.Post{
-		ID:          fanboxgo.NewOptString(entry.ID),
-		Title:       fanboxgo.NewOptString(entry.Title),
-		Status:      fanboxgo.NewOptPostStatus(entry.Status),
-		FeeRequired: fanboxgo.NewOptInt(fee),
-		Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
-			Blocks: blocks,
-		}),
+// リンクが FANBOX 上にある埋め込みを指しているか
+// pull した時点から URL を書き換えたリンクは、別の埋め込みとして扱う
+// FANBOX 上の埋め込みが分からなければ、pull したときにあった埋め込みだけを埋め込みとする
+func (e *Entry) hasEmbed(id string, url string) bool {
+	pulledUrl, pulled := e.Embeds[id]
+	if pulled && pulledUrl != url {
+		return false
+	}
+	if e.RemoteEmbeds != nil {
+		return e.RemoteEmbeds[id]
 	}
+	return pulled
 }
 
 func (e *Entry) getEmbedUrl(urlType fanboxgo.PostBodyUrlEmbedMapItemType, data fanboxgo.PostBodyUrlEmbedMapItem) (string, error) {
-	node, err := html.Parse(strings.NewReader(data.HTML.Value))
-	if err != nil {
-		return "", err
-	}
 	var url string
 	switch urlType {
-	case fanboxgo.PostBodyUrlEmbedMapItemTypeHTMLCard:
-		attr := node.FirstChild.FirstChild.NextSibling.FirstChild.FirstChild.FirstChild.Attr
-		url, err = e.iframelyClient.GetRealUrl(attr[0].Val)
+	case fanboxgo.PostBodyUrlEmbedMapItemTypeHTMLCard, fanboxgo.PostBodyUrlEmbedMapItemTypeHTML:
+		src, err := findEmbedSource(data.HTML.Value)
 		if err != nil {
-			return "", err
+			return "", &EmbedError{ID: data.ID.Value, Type: urlType, Reason: err.Error()}
+		}
+		url = src
+		// クライアントが無ければ、iframely の URL のまま残す
+		if strings.HasPrefix(src, "https://cdn.iframe.ly/") && e.iframelyClient != nil {
+			url, err = e.iframelyClient.GetRealUrl(src)
+			if err != nil {
+				// 解決できなくても、iframely の URL のまま残しておく
+				slog.Warn("failed to resolve embed", "url", src, "error", err)
+				url = src
+			}
 		}
-	case fanboxgo.PostBodyUrlEmbedMapItemTypeHTML:
-		attr := node.FirstChild.FirstChild.NextSibling.FirstChild.FirstChild.FirstChild.Attr
-		url = attr[0].Val
+		url = normalizeEmbedUrl(url)
 	case fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost:
 		url = fmt.Sprintf("https://%s.fanbox.cc/posts/%s", data.PostInfo.Value.CreatorId.Value, data.PostInfo.Value.ID.Value)
 	case fanboxgo.PostBodyUrlEmbedMapItemTypeDefault:
 		url = data.URL.Value
 	default:
-		return "", errors.New("unexpected url type")
+		return "", &EmbedError{ID: data.ID.Value, Type: urlType, Reason: "unexpected url type"}
 	}
 
 	return url, nil
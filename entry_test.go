@@ -63,10 +63,11 @@ func TestConvertPost(t *testing.T) {
 			e := Entry{}
 
 			// execute
-			e = *e.ConvertPost(&tt.post)
+			converted, err := e.ConvertPost(&tt.post)
 
 			// verify
-			assert.Equal(t, tt.want, e)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *converted)
 		})
 	}
 }
@@ -116,6 +117,117 @@ func TestConvertFanbox(t *testing.T) {
 				}),
 			},
 		},
+		{
+			name: "FANBOX 上にある埋め込みは ID だけを送り、新しいリンクは書いた行のまま段落にする",
+			entry: Entry{
+				ID:           "1000000",
+				Title:        "テスト投稿",
+				Status:       fanboxgo.PostStatusDraft,
+				Fee:          "0",
+				Body:         "[abc](https://example.com/old)\n[post](https://creator.fanbox.cc/posts/123)\n[リンク](https://example.com/new)",
+				Embeds:       map[string]string{"abc": "https://example.com/old", "post": "https://creator.fanbox.cc/posts/123"},
+				RemoteEmbeds: map[string]bool{"abc": true, "post": true},
+			},
+			want: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("テスト投稿"),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+					Blocks: []fanboxgo.PostBodyBlocksItem{
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("abc"),
+						},
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("post"),
+						},
+						{
+							Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+							Text: fanboxgo.NewOptString("[リンク](https://example.com/new)"),
+						},
+					},
+				}),
+			},
+		},
+		{
+			name: "FANBOX の投稿へのリンクは、fanbox.post の埋め込みとして作る",
+			entry: Entry{
+				ID:           "1000000",
+				Title:        "テスト投稿",
+				Status:       fanboxgo.PostStatusDraft,
+				Fee:          "0",
+				Body:         "[前回](https://creator.fanbox.cc/posts/123)\n[次回](https://www.fanbox.cc/@creator/posts/456)\n[トップ](https://www.fanbox.cc/posts/789)",
+				RemoteEmbeds: map[string]bool{},
+			},
+			want: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("テスト投稿"),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+					Blocks: []fanboxgo.PostBodyBlocksItem{
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("fanbox-post-123"),
+						},
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("fanbox-post-456"),
+						},
+						{
+							Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+							Text: fanboxgo.NewOptString("[トップ](https://www.fanbox.cc/posts/789)"),
+						},
+					},
+					UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{
+						"fanbox-post-123": {
+							ID:   fanboxgo.NewOptString("fanbox-post-123"),
+							Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost),
+							PostInfo: fanboxgo.NewOptPostBodyUrlEmbedMapItemPostInfo(fanboxgo.PostBodyUrlEmbedMapItemPostInfo{
+								ID:        fanboxgo.NewOptString("123"),
+								CreatorId: fanboxgo.NewOptString("creator"),
+							}),
+						},
+						"fanbox-post-456": {
+							ID:   fanboxgo.NewOptString("fanbox-post-456"),
+							Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost),
+							PostInfo: fanboxgo.NewOptPostBodyUrlEmbedMapItemPostInfo(fanboxgo.PostBodyUrlEmbedMapItemPostInfo{
+								ID:        fanboxgo.NewOptString("456"),
+								CreatorId: fanboxgo.NewOptString("creator"),
+							}),
+						},
+					}),
+				}),
+			},
+		},
+		{
+			name: "URL を書き換えたリンクは、元の埋め込みとして送らない",
+			entry: Entry{
+				ID:           "1000000",
+				Title:        "テスト投稿",
+				Status:       fanboxgo.PostStatusDraft,
+				Fee:          "0",
+				Body:         "[abc](https://example.com/changed) の続き",
+				Embeds:       map[string]string{"abc": "https://example.com/old"},
+				RemoteEmbeds: map[string]bool{"abc": true},
+			},
+			want: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("テスト投稿"),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+					Blocks: []fanboxgo.PostBodyBlocksItem{
+						{
+							Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+							Text: fanboxgo.NewOptString("[abc](https://example.com/changed) の続き"),
+						},
+					},
+				}),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -126,10 +238,11 @@ func TestConvertFanbox(t *testing.T) {
 			e := Entry{}
 
 			// execute
-			post := *e.ConvertFanbox(&tt.entry)
+			post, err := e.ConvertFanbox(&tt.entry)
 
 			// verify
-			assert.Equal(t, tt.want, post)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *post)
 		})
 	}
 }
@@ -0,0 +1,189 @@
+package epub
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// EPUB 3 の本を組み立てる
+// 章には XHTML の文書をそのまま渡す
+type Book struct {
+	Title    string
+	Author   string
+	Language string
+	ID       string
+	chapters []chapter
+	images   []image
+}
+
+type chapter struct {
+	id    string
+	title string
+	xhtml string
+}
+
+type file struct {
+	name string
+	data []byte
+}
+
+type image struct {
+	name      string
+	mediaType string
+	data      []byte
+}
+
+func NewBook(id string, title string, author string) *Book {
+	return &Book{
+		Title:    title,
+		Author:   author,
+		Language: "ja",
+		ID:       id,
+	}
+}
+
+// 章を追加し、本の中でのファイル名を返す
+func (b *Book) AddChapter(id string, title string, xhtml string) string {
+	b.chapters = append(b.chapters, chapter{id: id, title: title, xhtml: xhtml})
+	return chapterFile(id)
+}
+
+// 画像を追加し、章から参照するときのパスを返す
+func (b *Book) AddImage(name string, data []byte) (string, error) {
+	mediaType, err := imageMediaType(name)
+	if err != nil {
+		return "", err
+	}
+	b.images = append(b.images, image{name: name, mediaType: mediaType, data: data})
+	return path.Join("images", name), nil
+}
+
+func (b *Book) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	// mimetype は圧縮せずに先頭に置かなければならない
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = mimetype.Write([]byte("application/epub+zip"))
+	if err != nil {
+		return err
+	}
+
+	files := []file{
+		{"META-INF/container.xml", []byte(containerXml)},
+		{"OEBPS/content.opf", []byte(b.opf())},
+		{"OEBPS/nav.xhtml", []byte(b.nav())},
+	}
+	for _, c := range b.chapters {
+		files = append(files, file{path.Join("OEBPS", chapterFile(c.id)), []byte(c.xhtml)})
+	}
+	for _, i := range b.images {
+		files = append(files, file{path.Join("OEBPS", "images", i.name), i.data})
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(file.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+const containerXml = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (b *Book) opf() string {
+	var manifest, spine []string
+	manifest = append(manifest, `    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`)
+	for _, c := range b.chapters {
+		manifest = append(manifest, fmt.Sprintf(`    <item id="c%s" href="%s" media-type="application/xhtml+xml"/>`, html.EscapeString(c.id), chapterFile(c.id)))
+		spine = append(spine, fmt.Sprintf(`    <itemref idref="c%s"/>`, html.EscapeString(c.id)))
+	}
+	for n, i := range b.images {
+		manifest = append(manifest, fmt.Sprintf(`    <item id="img%d" href="images/%s" media-type="%s"/>`, n, html.EscapeString(i.name), i.mediaType))
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="bookid">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:creator>%s</dc:creator>
+    <dc:language>%s</dc:language>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+%s
+  </manifest>
+  <spine>
+%s
+  </spine>
+</package>
+`,
+		html.EscapeString(b.ID),
+		html.EscapeString(b.Title),
+		html.EscapeString(b.Author),
+		html.EscapeString(b.Language),
+		time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		strings.Join(manifest, "\n"),
+		strings.Join(spine, "\n"),
+	)
+}
+
+func (b *Book) nav() string {
+	var items []string
+	for _, c := range b.chapters {
+		items = append(items, fmt.Sprintf(`      <li><a href="%s">%s</a></li>`, chapterFile(c.id), html.EscapeString(c.title)))
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="%s">
+<head><meta charset="UTF-8"/><title>%s</title></head>
+<body>
+  <nav epub:type="toc">
+    <ol>
+%s
+    </ol>
+  </nav>
+</body>
+</html>
+`, html.EscapeString(b.Language), html.EscapeString(b.Title), strings.Join(items, "\n"))
+}
+
+func chapterFile(id string) string {
+	return fmt.Sprintf("post-%s.xhtml", id)
+}
+
+func imageMediaType(name string) (string, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg", nil
+	case ".png":
+		return "image/png", nil
+	case ".gif":
+		return "image/gif", nil
+	case ".webp":
+		return "image/webp", nil
+	default:
+		return "", fmt.Errorf("unsupported image type: %s", name)
+	}
+}
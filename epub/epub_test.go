@@ -0,0 +1,63 @@
+package epub_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync/epub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookWrite(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	book := NewBook("urn:fanboxsync:test", "まとめ", "fanbox")
+	book.AddChapter("1000000", "最初の投稿", "<html/>")
+	src, err := book.AddImage("abc.png", []byte("png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "images/abc.png", src)
+
+	// execute
+	buf := &bytes.Buffer{}
+	err = book.Write(buf)
+
+	// verify
+	assert.NoError(t, err)
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"mimetype",
+		"META-INF/container.xml",
+		"OEBPS/content.opf",
+		"OEBPS/nav.xhtml",
+		"OEBPS/post-1000000.xhtml",
+		"OEBPS/images/abc.png",
+	}, names)
+	assert.Equal(t, zip.Store, r.File[0].Method)
+
+	nav, err := r.File[3].Open()
+	assert.NoError(t, err)
+	navBytes, _ := io.ReadAll(nav)
+	assert.Contains(t, string(navBytes), `<a href="post-1000000.xhtml">最初の投稿</a>`)
+}
+
+func TestBookAddImageUnsupported(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	book := NewBook("urn:fanboxsync:test", "まとめ", "fanbox")
+
+	// execute
+	_, err := book.AddImage("abc.bmp", []byte("bmp"))
+
+	// verify
+	assert.Error(t, err)
+}
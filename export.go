@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// 静的サイトジェネレーターごとの、記事と画像の置き場所と front matter の違い
+type SiteFormat string
+
+const (
+	SiteFormatHugo   SiteFormat = "hugo"
+	SiteFormatJekyll SiteFormat = "jekyll"
+)
+
+// 有料の投稿をどう扱うか
+type PaidPolicy string
+
+const (
+	PaidPolicyKeep   PaidPolicy = "keep"   // そのまま書き出す
+	PaidPolicySkip   PaidPolicy = "skip"   // 書き出さない
+	PaidPolicyTeaser PaidPolicy = "teaser" // 冒頭だけ書き出す
+)
+
+type ExportOptions struct {
+	Format       SiteFormat
+	Paid         PaidPolicy
+	TeaserLength int    // teaser のときに残す行数
+	TeaserNote   string // teaser の最後に付ける文
+}
+
+// 書き出す記事
+// Assets は元の URL と、サイトの中での置き場所の対応
+type SitePost struct {
+	Path    string
+	Content string
+	Assets  map[string]string
+}
+
+type hugoFrontMatter struct {
+	Title string   `yaml:"title"`
+	Date  string   `yaml:"date"`
+	Draft bool     `yaml:"draft"`
+	Slug  string   `yaml:"slug"`
+	Tags  []string `yaml:"tags"`
+}
+
+type jekyllFrontMatter struct {
+	Layout    string   `yaml:"layout"`
+	Title     string   `yaml:"title"`
+	Date      string   `yaml:"date"`
+	Published bool     `yaml:"published"`
+	Slug      string   `yaml:"slug"`
+	Tags      []string `yaml:"tags"`
+}
+
+func (f SiteFormat) Validate() error {
+	switch f {
+	case SiteFormatHugo, SiteFormatJekyll:
+		return nil
+	default:
+		return fmt.Errorf("invalid format: %s", f)
+	}
+}
+
+func (p PaidPolicy) Validate() error {
+	switch p {
+	case PaidPolicyKeep, PaidPolicySkip, PaidPolicyTeaser:
+		return nil
+	default:
+		return fmt.Errorf("invalid paid policy: %s", p)
+	}
+}
+
+func (o ExportOptions) Validate() error {
+	err := o.Format.Validate()
+	if err != nil {
+		return err
+	}
+	err = o.Paid.Validate()
+	if err != nil {
+		return err
+	}
+	if o.TeaserLength < 0 {
+		return fmt.Errorf("invalid teaser length: %d", o.TeaserLength)
+	}
+	return nil
+}
+
+// 投稿を静的サイトジェネレーター向けの記事にする
+// 書き出さない投稿なら nil を返す
+func RenderSitePost(entry Entry, tags []string, opts ExportOptions) (*SitePost, error) {
+	paid := entry.Fee != "" && entry.Fee != "0"
+	if paid && opts.Paid == PaidPolicySkip {
+		return nil, nil
+	}
+
+	date := entry.PublishedAt
+	if date == "" {
+		date = entry.UpdatedAt
+	}
+	parsedTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, err
+	}
+
+	var assetDir, assetUrl, postPath string
+	switch opts.Format {
+	case SiteFormatHugo:
+		assetDir = path.Join("static", "images", entry.ID)
+		assetUrl = path.Join("/images", entry.ID)
+		postPath = path.Join("content", "posts", entry.ID+".md")
+	case SiteFormatJekyll:
+		assetDir = path.Join("assets", "images", entry.ID)
+		assetUrl = path.Join("/assets", "images", entry.ID)
+		postPath = path.Join("_posts", fmt.Sprintf("%s-%s.md", parsedTime.Format(time.DateOnly), entry.ID))
+	default:
+		return nil, fmt.Errorf("invalid format: %s", opts.Format)
+	}
+
+	lines := strings.Split(entry.Body, "\n")
+	// 短い投稿でも全文が読めてしまわないよう、最後の行は必ず落とす
+	if paid && opts.Paid == PaidPolicyTeaser {
+		lines = lines[:max(0, min(opts.TeaserLength, len(lines)-1))]
+		if opts.TeaserNote != "" {
+			lines = append(lines, "", opts.TeaserNote)
+		}
+	}
+
+	// 画像はサイトの中に置いたものを参照させる
+	assets := map[string]string{}
+	for i, line := range lines {
+		if matches := reMarkdownImage.FindStringSubmatch(line); len(matches) > 0 {
+			name := path.Base(strings.SplitN(matches[2], "?", 2)[0])
+			assets[matches[2]] = path.Join(assetDir, name)
+			lines[i] = fmt.Sprintf("![%s](%s)", matches[1], path.Join(assetUrl, name))
+		} else if matches := reMarkdownLink.FindStringSubmatch(line); len(matches) > 0 {
+			// 埋め込みの ID は読む人には意味が無いので、リンク先の URL を見せる
+			if _, exist := entry.Embeds[matches[1]]; exist {
+				lines[i] = fmt.Sprintf("[%s](%s)", matches[2], matches[2])
+			}
+		}
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	draft := entry.Status != "published"
+	var frontMatter any
+	switch opts.Format {
+	case SiteFormatHugo:
+		frontMatter = &hugoFrontMatter{
+			Title: entry.Title,
+			Date:  parsedTime.Format(time.RFC3339),
+			Draft: draft,
+			Slug:  entry.ID,
+			Tags:  tags,
+		}
+	case SiteFormatJekyll:
+		frontMatter = &jekyllFrontMatter{
+			Layout:    "post",
+			Title:     entry.Title,
+			Date:      parsedTime.Format("2006-01-02 15:04:05 -0700"),
+			Published: !draft,
+			Slug:      entry.ID,
+			Tags:      tags,
+		}
+	}
+	metaBytes, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SitePost{
+		Path:    postPath,
+		Content: fmt.Sprintf("---\n%s---\n\n%s\n", string(metaBytes), strings.Join(lines, "\n")),
+		Assets:  assets,
+	}, nil
+}
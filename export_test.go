@@ -0,0 +1,152 @@
+package main_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSitePost(t *testing.T) {
+	entry := Entry{
+		ID:          "1000000",
+		Title:       "テスト投稿",
+		Status:      fanboxgo.PostStatusPublished,
+		Fee:         "500",
+		Body:        "冒頭\n![img](https://downloads.fanbox.cc/images/post/1000000/abc.png)\n続き",
+		PublishedAt: "2024-05-10T12:00:00+09:00",
+	}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		tags  []string
+		opts  ExportOptions
+		want  *SitePost
+	}{
+		{
+			name:  "Hugo の形式で書き出せる",
+			entry: entry,
+			opts:  ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyKeep},
+			want: &SitePost{
+				Path:    "content/posts/1000000.md",
+				Content: "---\ntitle: テスト投稿\ndate: \"2024-05-10T12:00:00+09:00\"\ndraft: false\nslug: \"1000000\"\ntags: []\n---\n\n冒頭\n![img](/images/1000000/abc.png)\n続き\n",
+				Assets: map[string]string{
+					"https://downloads.fanbox.cc/images/post/1000000/abc.png": "static/images/1000000/abc.png",
+				},
+			},
+		},
+		{
+			name:  "Jekyll の形式で書き出せる",
+			entry: entry,
+			opts:  ExportOptions{Format: SiteFormatJekyll, Paid: PaidPolicyKeep},
+			want: &SitePost{
+				Path:    "_posts/2024-05-10-1000000.md",
+				Content: "---\nlayout: post\ntitle: テスト投稿\ndate: 2024-05-10 12:00:00 +0900\npublished: true\nslug: \"1000000\"\ntags: []\n---\n\n冒頭\n![img](/assets/images/1000000/abc.png)\n続き\n",
+				Assets: map[string]string{
+					"https://downloads.fanbox.cc/images/post/1000000/abc.png": "assets/images/1000000/abc.png",
+				},
+			},
+		},
+		{
+			name:  "有料の投稿は冒頭だけにできる",
+			entry: entry,
+			opts:  ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyTeaser, TeaserLength: 1, TeaserNote: "続きは FANBOX で"},
+			want: &SitePost{
+				Path:    "content/posts/1000000.md",
+				Content: "---\ntitle: テスト投稿\ndate: \"2024-05-10T12:00:00+09:00\"\ndraft: false\nslug: \"1000000\"\ntags: []\n---\n\n冒頭\n\n続きは FANBOX で\n",
+				Assets:  map[string]string{},
+			},
+		},
+		{
+			name:  "冒頭に残す行数より短い有料の投稿も、全文は書き出さない",
+			entry: entry,
+			opts:  ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyTeaser, TeaserLength: 5, TeaserNote: "続きは FANBOX で"},
+			want: &SitePost{
+				Path:    "content/posts/1000000.md",
+				Content: "---\ntitle: テスト投稿\ndate: \"2024-05-10T12:00:00+09:00\"\ndraft: false\nslug: \"1000000\"\ntags: []\n---\n\n冒頭\n![img](/images/1000000/abc.png)\n\n続きは FANBOX で\n",
+				Assets: map[string]string{
+					"https://downloads.fanbox.cc/images/post/1000000/abc.png": "static/images/1000000/abc.png",
+				},
+			},
+		},
+		{
+			name: "タグを書き出し、埋め込みはリンク先の URL で見せる",
+			entry: Entry{
+				ID:          "1000001",
+				Title:       "無料の投稿",
+				Status:      fanboxgo.PostStatusPublished,
+				Fee:         "0",
+				Body:        "[abc](https://example.com/)\n[リンク](https://example.org/)",
+				PublishedAt: "2024-05-10T12:00:00+09:00",
+				Embeds:      map[string]string{"abc": "https://example.com/"},
+			},
+			tags: []string{"イラスト", "日記"},
+			opts: ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyTeaser},
+			want: &SitePost{
+				Path:    "content/posts/1000001.md",
+				Content: "---\ntitle: 無料の投稿\ndate: \"2024-05-10T12:00:00+09:00\"\ndraft: false\nslug: \"1000001\"\ntags:\n- イラスト\n- 日記\n---\n\n[https://example.com/](https://example.com/)\n[リンク](https://example.org/)\n",
+				Assets:  map[string]string{},
+			},
+		},
+		{
+			name:  "有料の投稿は書き出さないこともできる",
+			entry: entry,
+			opts:  ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicySkip},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			sitePost, err := RenderSitePost(tt.entry, tt.tags, tt.opts)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, sitePost)
+		})
+	}
+}
+
+func TestExportOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ExportOptions
+		wantErr bool
+	}{
+		{
+			name: "正しい設定",
+			opts: ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyTeaser, TeaserLength: 0},
+		},
+		{
+			name:    "知らない形式",
+			opts:    ExportOptions{Format: SiteFormat("gatsby"), Paid: PaidPolicyKeep},
+			wantErr: true,
+		},
+		{
+			name:    "冒頭に残す行数が負",
+			opts:    ExportOptions{Format: SiteFormatHugo, Paid: PaidPolicyTeaser, TeaserLength: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			err := tt.opts.Validate()
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package fanbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// 変更を伴う API を呼ばずに、送るはずだったリクエストを書き出す
+// 読み取りだけの API はそのまま呼ぶ
+type dryRunClient struct {
+	client fanboxgo.Invoker
+	w      io.Writer
+}
+
+const redacted = "<redacted>"
+
+// 送信先の CustomFanbox を dry-run 用に差し替えたものを返す
+func NewDryRunFanbox(f *CustomFanbox, w io.Writer) *CustomFanbox {
+	return &CustomFanbox{
+		Client: &dryRunClient{
+			client: f.Client,
+			w:      w,
+		},
+		SecurityStore: f.SecurityStore,
+		HttpClient:    f.HttpClient,
+		defaultParams: f.defaultParams,
+	}
+}
+
+func (d *dryRunClient) print(operation string, request any) error {
+	_, err := fmt.Fprintf(d.w, "%s ", operation)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(d.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(request)
+}
+
+// post.update はフォームで送られるため、送られるフィールドをそのまま並べる
+type dryRunUpdatePostReq struct {
+	PostId                    string   `json:"postId"`
+	Status                    string   `json:"status"`
+	FeeRequired               string   `json:"feeRequired"`
+	Title                     string   `json:"title"`
+	CommentingPermissionScope string   `json:"commentingPermissionScope"`
+	Body                      string   `json:"body"`
+	Tags                      []string `json:"tags"`
+	Tt                        string   `json:"tt"`
+}
+
+func (d *dryRunClient) CreatePost(ctx context.Context, request fanboxgo.OptCreatePostReq, params fanboxgo.CreatePostParams) (fanboxgo.CreatePostRes, error) {
+	raw, err := request.Value.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	err = d.print("post.create", json.RawMessage(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &fanboxgo.Create{
+		Body: fanboxgo.NewOptCreateBody(fanboxgo.CreateBody{
+			PostId: fanboxgo.NewOptString("dry-run"),
+		}),
+	}, nil
+}
+
+func (d *dryRunClient) UpdatePost(ctx context.Context, request fanboxgo.OptUpdatePostReq, params fanboxgo.UpdatePostParams) (fanboxgo.UpdatePostRes, error) {
+	req := request.Value
+	tt := req.Tt.Value
+	if req.Tt.Set {
+		tt = redacted
+	}
+	err := d.print("post.update", &dryRunUpdatePostReq{
+		PostId:                    req.PostId.Value,
+		Status:                    string(req.Status.Value),
+		FeeRequired:               req.FeeRequired.Value,
+		Title:                     req.Title.Value,
+		CommentingPermissionScope: string(req.CommentingPermissionScope.Value),
+		Body:                      req.Body.Value,
+		Tags:                      req.Tags,
+		Tt:                        tt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fanboxgo.Update{
+		Body: fanboxgo.NewOptPost(fanboxgo.Post{
+			ID:    request.Value.PostId,
+			Title: request.Value.Title,
+		}),
+	}, nil
+}
+
+func (d *dryRunClient) DeletePost(ctx context.Context, request fanboxgo.OptDeletePostReq, params fanboxgo.DeletePostParams) (fanboxgo.DeletePostRes, error) {
+	raw, err := request.Value.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	err = d.print("post.delete", json.RawMessage(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &fanboxgo.Delete{}, nil
+}
+
+func (d *dryRunClient) GetEditablePost(ctx context.Context, params fanboxgo.GetEditablePostParams) (fanboxgo.GetEditablePostRes, error) {
+	return d.client.GetEditablePost(ctx, params)
+}
+
+func (d *dryRunClient) ListManagedPosts(ctx context.Context, params fanboxgo.ListManagedPostsParams) (fanboxgo.ListManagedPostsRes, error) {
+	return d.client.ListManagedPosts(ctx, params)
+}
@@ -0,0 +1,61 @@
+package fanbox_test
+
+import (
+	"bytes"
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync/fanbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunPushPost(t *testing.T) {
+	tests := []struct {
+		name  string
+		posts map[string]fanboxgo.Post
+		post  fanboxgo.Post
+		want  fanboxgo.Post
+	}{
+		{
+			name: "リクエストを表示するだけで、投稿は更新されない",
+			posts: map[string]fanboxgo.Post{
+				"1000000": {
+					ID:    fanboxgo.NewOptString("1000000"),
+					Title: fanboxgo.NewOptString("変更前のタイトル"),
+				},
+			},
+			post: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("変更後のタイトル"),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Status:      fanboxgo.NewOptPostStatus("draft"),
+			},
+			want: fanboxgo.Post{
+				ID:    fanboxgo.NewOptString("1000000"),
+				Title: fanboxgo.NewOptString("変更前のタイトル"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			client := NewFakeFanbox(tt.posts)
+			out := &bytes.Buffer{}
+			testFanbox := NewDryRunFanbox(NewTestFanbox(client), out)
+
+			// execute
+			_, err := testFanbox.PushPost(&tt.post, nil)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Contains(t, out.String(), "post.update")
+			assert.Contains(t, out.String(), "変更後のタイトル")
+			assert.Contains(t, out.String(), `"tt": "<redacted>"`)
+			post, _ := testFanbox.GetPost(tt.post.ID.Value)
+			assert.Equal(t, tt.want, post)
+		})
+	}
+}
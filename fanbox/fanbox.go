@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 
 	fanboxgo "github.com/defaultcf/fanbox-go"
 )
@@ -26,12 +28,43 @@ func (s SecurityStore) SessionId(ctx context.Context, operationName string) (fan
 	}, nil
 }
 
+// 投稿の種類
+// FANBOX には text, image, file などの投稿もあるが、fanbox-go は article しか作れず本文の形も持たないため、article しか扱わない
+type PostType string
+
+const (
+	PostTypeArticle PostType = "article"
+)
+
+func (t PostType) Validate() error {
+	switch t {
+	case PostTypeArticle:
+		return nil
+	default:
+		return fmt.Errorf("unsupported post type: %s", t)
+	}
+}
+
 type CustomFanbox struct {
 	Client        fanboxgo.Invoker
 	SecurityStore SecurityStore
+	HttpClient    httpClient // fanbox-go を通さずに API を叩くときに使う
 	defaultParams defaultParams
 }
 
+// fanbox-go の Post が持たない、投稿の種類やタグ
+// 本文のブロックも、fanbox-go が知らない項目を落とさないよう受け取ったまま持つ
+type RawPost struct {
+	Type PostType    `json:"type"`
+	Tags []string    `json:"tags"`
+	Body RawPostBody `json:"body"`
+}
+
+type RawPostBody struct {
+	Blocks  json.RawMessage `json:"blocks"`
+	FileMap json.RawMessage `json:"fileMap,omitempty"` // fanbox-go の PostBody に無い添付ファイル
+}
+
 type defaultParams struct {
 	origin    string
 	userAgent string
@@ -54,14 +87,20 @@ func NewFanbox(csrfToken, sessionId, userAgent string) (*CustomFanbox, error) {
 	return &CustomFanbox{
 		Client:        c,
 		SecurityStore: s,
+		HttpClient:    &http.Client{},
 		defaultParams: d,
 	}, nil
 }
 
+// client が Do も持っていれば、fanbox-go を通さない API にも使う
 func NewTestFanbox(client fanboxgo.Invoker) *CustomFanbox {
-	return &CustomFanbox{
+	f := &CustomFanbox{
 		Client: client,
 	}
+	if h, ok := client.(httpClient); ok {
+		f.HttpClient = h
+	}
+	return f
 }
 
 func (f CustomFanbox) GetPosts() ([]fanboxgo.Post, error) {
@@ -87,6 +126,24 @@ func (f CustomFanbox) GetPost(postId string) (fanboxgo.Post, error) {
 	return res.(*fanboxgo.Get).Body.Value, nil
 }
 
+// post.getEditable を直接叩き、fanbox-go の Post が捨てている項目を取り出す
+func (f CustomFanbox) GetRawPost(postId string) (RawPost, error) {
+	if f.HttpClient == nil {
+		return RawPost{}, errors.New("http client is not set")
+	}
+	public := PublicFanbox{
+		HttpClient:    f.HttpClient,
+		sessionId:     f.SecurityStore.rawSessionId,
+		defaultParams: f.defaultParams,
+	}
+	post := RawPost{}
+	err := public.get(fmt.Sprintf("https://api.fanbox.cc/post.getEditable?postId=%s", url.QueryEscape(postId)), &post)
+	if err != nil {
+		return RawPost{}, err
+	}
+	return post, nil
+}
+
 func (f CustomFanbox) CreatePost() (string, error) {
 	res, err := f.Client.CreatePost(context.TODO(),
 		fanboxgo.NewOptCreatePostReq(fanboxgo.CreatePostReq{Type: fanboxgo.CreatePostReqTypeArticle}),
@@ -107,7 +164,20 @@ func (f CustomFanbox) CreatePost() (string, error) {
 	}
 }
 
-func (f CustomFanbox) PushPost(post *fanboxgo.Post) (fanboxgo.Post, error) {
+// tags には今のタグを渡す。post.update は送らなかったタグを消してしまう
+func (f CustomFanbox) PushPost(post *fanboxgo.Post, tags []string) (fanboxgo.Post, error) {
+	bodyJson, err := ConvertJson(&post.Body.Value)
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+	return f.updatePost(post, bodyJson, tags)
+}
+
+func (f CustomFanbox) updatePost(post *fanboxgo.Post, bodyJson string, tags []string) (fanboxgo.Post, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+
 	var commentingPermissionScope fanboxgo.UpdatePostReqCommentingPermissionScope
 	if post.FeeRequired.Value == 0 {
 		commentingPermissionScope = fanboxgo.UpdatePostReqCommentingPermissionScopeEveryone
@@ -115,10 +185,6 @@ func (f CustomFanbox) PushPost(post *fanboxgo.Post) (fanboxgo.Post, error) {
 		commentingPermissionScope = fanboxgo.UpdatePostReqCommentingPermissionScopeSupporters
 	}
 
-	bodyJson, err := convertJson(&post.Body.Value.Blocks)
-	if err != nil {
-		return fanboxgo.Post{}, err
-	}
 	res, err := f.Client.UpdatePost(context.TODO(),
 		fanboxgo.NewOptUpdatePostReq(fanboxgo.UpdatePostReq{
 			PostId:                    post.ID,
@@ -127,7 +193,7 @@ func (f CustomFanbox) PushPost(post *fanboxgo.Post) (fanboxgo.Post, error) {
 			Title:                     post.Title,
 			CommentingPermissionScope: fanboxgo.NewOptUpdatePostReqCommentingPermissionScope(commentingPermissionScope),
 			Body:                      fanboxgo.NewOptString(bodyJson),
-			Tags:                      []string{},
+			Tags:                      tags,
 			Tt:                        fanboxgo.NewOptString(f.SecurityStore.rawCsrfToken),
 		}),
 		fanboxgo.UpdatePostParams{
@@ -147,6 +213,34 @@ func (f CustomFanbox) PushPost(post *fanboxgo.Post) (fanboxgo.Post, error) {
 	}
 }
 
+// 本文などは手元のファイルではなく現在の投稿のまま、ステータスだけを変更する
+func (f CustomFanbox) UpdateStatus(postId string, status fanboxgo.PostStatus) (fanboxgo.Post, error) {
+	post, err := f.GetPost(postId)
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+	if !post.ID.Set {
+		return fanboxgo.Post{}, fmt.Errorf("post %s is not found", postId)
+	}
+
+	raw, err := f.GetRawPost(postId)
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+	err = raw.Type.Validate()
+	if err != nil {
+		return fanboxgo.Post{}, fmt.Errorf("cannot update %s: %w", postId, err)
+	}
+
+	// fanbox-go を通すと落ちる項目があるので、本文とタグは取得したものをそのまま送り返す
+	blocks := string(raw.Body.Blocks)
+	if blocks == "" || blocks == "null" {
+		blocks = "[]"
+	}
+	post.Status = fanboxgo.NewOptPostStatus(status)
+	return f.updatePost(&post, blocks, raw.Tags)
+}
+
 func (f CustomFanbox) DeletePost(postId string) error {
 	_, err := f.Client.DeletePost(
 		context.TODO(),
@@ -162,16 +256,28 @@ func (f CustomFanbox) DeletePost(postId string) error {
 	return nil
 }
 
-func convertJson(body *[]fanboxgo.PostBodyBlocksItem) (string, error) {
-	jsonBytes, err := json.Marshal(body)
-	if err != nil {
-		return "", err
+// 本文のブロックを post.update に送る JSON にする
+// 画像や埋め込みの中身は FANBOX 上にあるものが使われるので、普段はブロックだけを送る
+// urlEmbedMap があれば、post.getEditable が返すのと同じ本文のオブジェクトにして一緒に送る
+func ConvertJson(body *fanboxgo.PostBody) (string, error) {
+	blocks := body.Blocks
+	// 本文が空なら、"null" ではなく空の配列を送る
+	if blocks == nil {
+		blocks = []fanboxgo.PostBodyBlocksItem{}
 	}
-	jsonString := string(jsonBytes)
 
-	// 本文が空なら、"null" ではなく空の配列を返す
-	if jsonString == "null" {
-		return "[]", nil
+	var jsonBytes []byte
+	var err error
+	if len(body.UrlEmbedMap.Value) == 0 {
+		jsonBytes, err = json.Marshal(blocks)
+	} else {
+		jsonBytes, err = json.Marshal(struct {
+			Blocks      []fanboxgo.PostBodyBlocksItem `json:"blocks"`
+			UrlEmbedMap fanboxgo.PostBodyUrlEmbedMap  `json:"urlEmbedMap"`
+		}{blocks, body.UrlEmbedMap.Value})
+	}
+	if err != nil {
+		return "", err
 	}
-	return jsonString, nil
+	return string(jsonBytes), nil
 }
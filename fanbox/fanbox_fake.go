@@ -1,13 +1,18 @@
 package fanbox
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"maps"
+	"net/http"
 	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	fanboxgo "github.com/defaultcf/fanbox-go"
 )
@@ -15,6 +20,9 @@ import (
 type fakeFanbox struct {
 	customFanbox CustomFanbox
 	posts        map[string]fanboxgo.Post
+	raws         map[string]RawPost // 無ければタグの無い article として返す
+	errs         map[string]error   // API の名前ごとに、呼ばれたら返すエラー
+	noUpdatedAt  map[string]bool    // 更新の結果に更新日時を付けない投稿
 }
 
 func NewFakeFanbox(posts map[string]fanboxgo.Post) *fakeFanbox {
@@ -24,11 +32,74 @@ func NewFakeFanbox(posts map[string]fanboxgo.Post) *fakeFanbox {
 			Client:        client,
 			SecurityStore: SecurityStore{},
 		},
-		posts: posts,
+		posts:       posts,
+		raws:        map[string]RawPost{},
+		errs:        map[string]error{},
+		noUpdatedAt: map[string]bool{},
 	}
 }
 
+// operation の API が呼ばれたら err を返すようにする
+func (f fakeFanbox) SetError(operation string, err error) {
+	f.errs[operation] = err
+}
+
+// 投稿の種類やタグを設定する
+func (f fakeFanbox) SetRawPost(postId string, raw RawPost) {
+	f.raws[postId] = raw
+}
+
+// postId を更新した結果に、更新日時を付けないようにする
+func (f fakeFanbox) SetNoUpdatedAt(postId string) {
+	f.noUpdatedAt[postId] = true
+}
+
+func (f fakeFanbox) raw(postId string) RawPost {
+	raw, exist := f.raws[postId]
+	if !exist {
+		return RawPost{Type: PostTypeArticle, Tags: []string{}}
+	}
+	return raw
+}
+
+// fanbox-go を通さない post.getEditable に応える
+func (f fakeFanbox) Do(req *http.Request) (*http.Response, error) {
+	post, exist := f.posts[req.URL.Query().Get("postId")]
+	if req.URL.Path != "/post.getEditable" || !exist {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"error":"general_error"}`))),
+		}, nil
+	}
+
+	raw := f.raw(post.ID.Value)
+	blocks := post.Body.Value.Blocks
+	if blocks == nil {
+		blocks = []fanboxgo.PostBodyBlocksItem{}
+	}
+	body := map[string]any{"blocks": blocks}
+	if raw.Body.FileMap != nil {
+		body["fileMap"] = raw.Body.FileMap
+	}
+	response, err := json.Marshal(map[string]any{"body": map[string]any{
+		"id":   post.ID.Value,
+		"type": raw.Type,
+		"tags": raw.Tags,
+		"body": body,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(response)),
+	}, nil
+}
+
 func (f fakeFanbox) CreatePost(ctx context.Context, request fanboxgo.OptCreatePostReq, params fanboxgo.CreatePostParams) (fanboxgo.CreatePostRes, error) {
+	if err := f.errs["post.create"]; err != nil {
+		return nil, err
+	}
 	id := 1000000 + len(f.posts)
 	for {
 		_, exist := f.posts[fmt.Sprint(id)]
@@ -63,30 +134,59 @@ func (f fakeFanbox) ListManagedPosts(ctx context.Context, params fanboxgo.ListMa
 }
 
 func (f fakeFanbox) UpdatePost(ctx context.Context, request fanboxgo.OptUpdatePostReq, params fanboxgo.UpdatePostParams) (fanboxgo.UpdatePostRes, error) {
+	if err := f.errs["post.update"]; err != nil {
+		return nil, err
+	}
 	fee, err := strconv.Atoi(request.Value.FeeRequired.Value)
 	if err != nil {
 		return nil, err
 	}
 
-	blocks := []fanboxgo.PostBodyBlocksItem{}
-	err = json.Unmarshal([]byte(request.Value.Body.Value), &blocks)
+	// ブロックだけが送られたら、画像や埋め込みはサーバー上のものが残る
+	// 本文のオブジェクトで送られたら、埋め込みは送られたものにする
+	sent := fanboxgo.PostBody{}
+	if strings.HasPrefix(request.Value.Body.Value, "{") {
+		err = sent.UnmarshalJSON([]byte(request.Value.Body.Value))
+	} else {
+		err = json.Unmarshal([]byte(request.Value.Body.Value), &sent.Blocks)
+	}
 	if err != nil {
 		return nil, err
 	}
+	stored := f.posts[request.Value.PostId.Value]
+	body := stored.Body.Value
+	body.Blocks = sent.Blocks
+	if sent.UrlEmbedMap.Set {
+		body.UrlEmbedMap = sent.UrlEmbedMap
+	}
+	// 更新するたびに、前の更新日時から 1 分進める
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("Asia/Tokyo", 9*60*60))
+	if previous, err := time.Parse(time.RFC3339, stored.UpdatedAt.Value); err == nil {
+		updatedAt = previous.Add(time.Minute)
+	}
+	raw := f.raw(request.Value.PostId.Value)
+	raw.Tags = request.Value.Tags
+	f.raws[request.Value.PostId.Value] = raw
 
 	f.posts[request.Value.PostId.Value] = fanboxgo.Post{
 		ID:          request.Value.PostId,
 		Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatus(request.Value.Status.Value)),
 		FeeRequired: fanboxgo.NewOptInt(fee),
 		Title:       fanboxgo.NewOptString(request.Value.Title.Value),
-		Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
-			Blocks: blocks,
-		}),
+		UpdatedAt:   fanboxgo.NewOptString(updatedAt.Format(time.RFC3339)),
+		Body:        fanboxgo.NewOptPostBody(body),
 	}
-	return &fanboxgo.Update{Body: fanboxgo.NewOptPost(f.posts[request.Value.PostId.Value])}, nil
+	updated := f.posts[request.Value.PostId.Value]
+	if f.noUpdatedAt[request.Value.PostId.Value] {
+		updated.UpdatedAt = fanboxgo.OptString{}
+	}
+	return &fanboxgo.Update{Body: fanboxgo.NewOptPost(updated)}, nil
 }
 
 func (f fakeFanbox) DeletePost(ctx context.Context, request fanboxgo.OptDeletePostReq, params fanboxgo.DeletePostParams) (fanboxgo.DeletePostRes, error) {
+	if err := f.errs["post.delete"]; err != nil {
+		return nil, err
+	}
 	delete(f.posts, request.Value.PostId)
 	return &fanboxgo.Delete{}, nil
 }
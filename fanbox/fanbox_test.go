@@ -1,6 +1,13 @@
 package fanbox_test
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	fanboxgo "github.com/defaultcf/fanbox-go"
@@ -165,6 +172,7 @@ func TestPushPost(t *testing.T) {
 					Title:       fanboxgo.NewOptString("変更前のタイトル"),
 					FeeRequired: fanboxgo.NewOptInt(0),
 					Status:      fanboxgo.NewOptPostStatus("draft"),
+					UpdatedAt:   fanboxgo.NewOptString("2024-05-10T12:00:00+09:00"),
 					Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
 						Blocks: []fanboxgo.PostBodyBlocksItem{
 							{
@@ -194,6 +202,7 @@ func TestPushPost(t *testing.T) {
 				Title:       fanboxgo.NewOptString("変更後のタイトル"),
 				FeeRequired: fanboxgo.NewOptInt(500),
 				Status:      fanboxgo.NewOptPostStatus("draft"),
+				UpdatedAt:   fanboxgo.NewOptString("2024-05-10T12:01:00+09:00"),
 				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
 					Blocks: []fanboxgo.PostBodyBlocksItem{
 						{
@@ -215,7 +224,7 @@ func TestPushPost(t *testing.T) {
 			testFanbox := NewTestFanbox(client)
 
 			// execute
-			res, err := testFanbox.PushPost(&tt.post)
+			res, err := testFanbox.PushPost(&tt.post, nil)
 
 			// verify
 			assert.NoError(t, err)
@@ -265,3 +274,328 @@ func TestDeletePost(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateStatus(t *testing.T) {
+	body := fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+		Blocks: []fanboxgo.PostBodyBlocksItem{
+			{
+				Type: fanboxgo.NewOptPostBodyBlocksItemType("p"),
+				Text: fanboxgo.NewOptString("サーバー上のテキスト"),
+			},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		posts    map[string]fanboxgo.Post
+		id       string
+		raws     map[string]RawPost
+		status   fanboxgo.PostStatus
+		want     fanboxgo.Post
+		wantTags []string
+		wantErr  bool
+	}{
+		{
+			name: "本文はそのままでステータスだけが変わる",
+			posts: map[string]fanboxgo.Post{
+				"1000000": {
+					ID:          fanboxgo.NewOptString("1000000"),
+					Title:       fanboxgo.NewOptString("タイトル"),
+					FeeRequired: fanboxgo.NewOptInt(0),
+					Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+					Body:        body,
+				},
+			},
+			id: "1000000",
+			raws: map[string]RawPost{
+				"1000000": {Type: PostTypeArticle, Tags: []string{"日記", "お知らせ"}},
+			},
+			status: fanboxgo.PostStatusPublished,
+			want: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("タイトル"),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+				UpdatedAt:   fanboxgo.NewOptString("2024-01-01T00:00:00+09:00"),
+				Body:        body,
+			},
+			wantTags: []string{"日記", "お知らせ"},
+		},
+		{
+			name: "article 以外の投稿は本文を壊さないよう変更しない",
+			posts: map[string]fanboxgo.Post{
+				"1000000": {
+					ID:     fanboxgo.NewOptString("1000000"),
+					Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				},
+			},
+			id: "1000000",
+			raws: map[string]RawPost{
+				"1000000": {Type: "image"},
+			},
+			status:  fanboxgo.PostStatusPublished,
+			wantErr: true,
+		},
+		{
+			name:    "投稿が無ければエラーが返る",
+			posts:   map[string]fanboxgo.Post{},
+			id:      "1000000",
+			status:  fanboxgo.PostStatusPublished,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			client := NewFakeFanbox(tt.posts)
+			for id, raw := range tt.raws {
+				client.SetRawPost(id, raw)
+			}
+			testFanbox := NewTestFanbox(client)
+
+			// execute
+			_, err := testFanbox.UpdateStatus(tt.id, tt.status)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			post, _ := testFanbox.GetPost(tt.id)
+			assert.Equal(t, tt.want, post)
+			raw, _ := testFanbox.GetRawPost(tt.id)
+			assert.Equal(t, tt.wantTags, raw.Tags)
+		})
+	}
+}
+
+// fanbox-go のクライアントが実際に送るリクエストを記録するサーバー
+func newRecordingServer(t *testing.T, responses map[string]string) (*httptest.Server, map[string]url.Values) {
+	t.Helper()
+	requests := map[string]url.Values{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests[r.URL.Path] = r.Form
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, responses[r.URL.Path])
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestPushPostRequest(t *testing.T) {
+	blocks := []fanboxgo.PostBodyBlocksItem{
+		{
+			Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+			Text: fanboxgo.NewOptString("本文"),
+		},
+		{
+			Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+			UrlEmbedId: fanboxgo.NewOptString("abc"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     fanboxgo.PostBody
+		wantBody string
+	}{
+		{
+			name:     "埋め込みの対応が無ければ、ブロックの配列だけを送る",
+			body:     fanboxgo.PostBody{Blocks: blocks},
+			wantBody: `[{"type":"p","text":"本文"},{"type":"url_embed","urlEmbedId":"abc"}]`,
+		},
+		{
+			name: "作る埋め込みがあれば、本文のオブジェクトにして一緒に送る",
+			body: fanboxgo.PostBody{
+				Blocks: blocks,
+				UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{
+					"abc": {
+						ID:   fanboxgo.NewOptString("abc"),
+						Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeFanboxPost),
+						PostInfo: fanboxgo.NewOptPostBodyUrlEmbedMapItemPostInfo(fanboxgo.PostBodyUrlEmbedMapItemPostInfo{
+							ID:        fanboxgo.NewOptString("123"),
+							CreatorId: fanboxgo.NewOptString("creator"),
+						}),
+					},
+				}),
+			},
+			wantBody: `{"blocks":[{"type":"p","text":"本文"},{"type":"url_embed","urlEmbedId":"abc"}],"urlEmbedMap":{"abc":{"id":"abc","type":"fanbox.post","postInfo":{"id":"123","creatorId":"creator"}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			server, requests := newRecordingServer(t, map[string]string{
+				"/post.update": `{"body":{"id":"1000000","updatedAt":"2024-05-10T12:00:00+09:00"}}`,
+			})
+			client, err := fanboxgo.NewClient(server.URL, SecurityStore{})
+			assert.NoError(t, err)
+			testFanbox := NewTestFanbox(client)
+			post := fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("タイトル"),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+				Body:        fanboxgo.NewOptPostBody(tt.body),
+			}
+
+			// execute
+			res, err := testFanbox.PushPost(&post, []string{"日記"})
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, "2024-05-10T12:00:00+09:00", res.UpdatedAt.Value)
+			form := requests["/post.update"]
+			assert.Equal(t, "1000000", form.Get("postId"))
+			assert.Equal(t, tt.wantBody, form.Get("body"))
+			assert.Equal(t, []string{"日記"}, form["tags"])
+		})
+	}
+}
+
+func TestGetRawPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		raws    map[string]RawPost
+		id      string
+		want    RawPost
+		wantErr bool
+	}{
+		{
+			name: "種類とタグが取得できる",
+			raws: map[string]RawPost{
+				"1000000": {Type: "text", Tags: []string{"日記"}},
+			},
+			id:   "1000000",
+			want: RawPost{Type: "text", Tags: []string{"日記"}, Body: RawPostBody{Blocks: []byte("[]")}},
+		},
+		{
+			name: "添付ファイルがあれば fileMap をそのまま取得できる",
+			raws: map[string]RawPost{
+				"1000000": {Type: PostTypeArticle, Tags: []string{}, Body: RawPostBody{FileMap: []byte(`{"file":{"id":"file"}}`)}},
+			},
+			id:   "1000000",
+			want: RawPost{Type: PostTypeArticle, Tags: []string{}, Body: RawPostBody{Blocks: []byte("[]"), FileMap: []byte(`{"file":{"id":"file"}}`)}},
+		},
+		{
+			name: "設定していなければタグの無い article になる",
+			id:   "1000000",
+			want: RawPost{Type: PostTypeArticle, Tags: []string{}, Body: RawPostBody{Blocks: []byte("[]")}},
+		},
+		{
+			name:    "投稿が無ければエラーが返る",
+			id:      "1000001",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			client := NewFakeFanbox(map[string]fanboxgo.Post{
+				"1000000": {ID: fanboxgo.NewOptString("1000000")},
+			})
+			for id, raw := range tt.raws {
+				client.SetRawPost(id, raw)
+			}
+			testFanbox := NewTestFanbox(client)
+
+			// execute
+			raw, err := testFanbox.GetRawPost(tt.id)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, raw)
+		})
+	}
+}
+
+type httpClientFunc func(req *http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetRawPostRequest(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	testFanbox, err := NewFanbox("csrf", "session", "fanboxsync")
+	assert.NoError(t, err)
+	var sent *http.Request
+	testFanbox.HttpClient = httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"body":{"id":"1000000","type":"image","tags":["イラスト"],"body":{"text":"","imageIds":["abc"]}}}`)),
+		}, nil
+	})
+
+	// execute
+	raw, err := testFanbox.GetRawPost("1000000")
+
+	// verify
+	assert.NoError(t, err)
+	assert.Equal(t, RawPost{Type: "image", Tags: []string{"イラスト"}}, raw)
+	assert.Equal(t, "https://api.fanbox.cc/post.getEditable?postId=1000000", sent.URL.String())
+	cookie, err := sent.Cookie("FANBOXSESSID")
+	assert.NoError(t, err)
+	assert.Equal(t, "session", cookie.Value)
+	assert.Equal(t, "https://www.fanbox.cc", sent.Header.Get("Origin"))
+}
+
+func TestUpdateStatusRequest(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	blocks := `[{"type":"p","text":"本文"},{"type":"url_embed","urlEmbedId":"abc"}]`
+	server, requests := newRecordingServer(t, map[string]string{
+		"/post.getEditable": `{"body":{"id":"1000000","title":"タイトル","status":"draft","feeRequired":0,"type":"article","tags":["日記"],"body":{"blocks":` + blocks + `}}}`,
+		"/post.update":      `{"body":{"id":"1000000","status":"published"}}`,
+	})
+	client, err := fanboxgo.NewClient(server.URL, SecurityStore{})
+	assert.NoError(t, err)
+	testFanbox := NewTestFanbox(client)
+	// post.getEditable を直接叩くリクエストも、記録するサーバーに向ける
+	testFanbox.HttpClient = httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		target, err := url.Parse(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		return http.DefaultClient.Do(req)
+	})
+
+	// execute
+	_, err = testFanbox.UpdateStatus("1000000", fanboxgo.PostStatusPublished)
+
+	// verify
+	assert.NoError(t, err)
+	form := requests["/post.update"]
+	assert.Equal(t, "published", form.Get("status"))
+	assert.Equal(t, blocks, form.Get("body"))
+	assert.Equal(t, []string{"日記"}, form["tags"])
+}
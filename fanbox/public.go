@@ -0,0 +1,163 @@
+package fanbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// fanbox-go は自分の投稿を管理する API しか持たないため、
+// 他のクリエイターの投稿を閲覧する API はここで直接叩く
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type PublicFanbox struct {
+	HttpClient    httpClient
+	sessionId     string
+	defaultParams defaultParams
+}
+
+// 閲覧した投稿
+// 支援していないなどの理由で本文が読めない場合は IsRestricted が true になり、本文は空になる
+type PublicPost struct {
+	Post         fanboxgo.Post
+	IsRestricted bool
+}
+
+type publicPostApi struct {
+	ID                string          `json:"id"`
+	Title             string          `json:"title"`
+	FeeRequired       int             `json:"feeRequired"`
+	PublishedDatetime string          `json:"publishedDatetime"`
+	UpdatedDatetime   string          `json:"updatedDatetime"`
+	IsRestricted      bool            `json:"isRestricted"`
+	Body              json.RawMessage `json:"body"`
+}
+
+func NewPublicFanbox(httpClient httpClient, sessionId, userAgent string) *PublicFanbox {
+	return &PublicFanbox{
+		HttpClient: httpClient,
+		sessionId:  sessionId,
+		defaultParams: defaultParams{
+			origin:    "https://www.fanbox.cc",
+			userAgent: userAgent,
+		},
+	}
+}
+
+// クリエイターの投稿の一覧を取得する
+// 一覧には本文が含まれないため、本文は GetCreatorPost で取得する
+func (f PublicFanbox) GetCreatorPosts(creatorId string) ([]PublicPost, error) {
+	pages := []string{}
+	err := f.get(fmt.Sprintf("https://api.fanbox.cc/post.paginateCreator?creatorId=%s", url.QueryEscape(creatorId)), &pages)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []PublicPost{}
+	for _, page := range pages {
+		items := []publicPostApi{}
+		err := f.get(page, &items)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			post, err := item.convert()
+			if err != nil {
+				return nil, err
+			}
+			posts = append(posts, post)
+		}
+	}
+
+	return posts, nil
+}
+
+func (f PublicFanbox) GetCreatorPost(postId string) (PublicPost, error) {
+	item := publicPostApi{}
+	err := f.get(fmt.Sprintf("https://api.fanbox.cc/post.info?postId=%s", url.QueryEscape(postId)), &item)
+	if err != nil {
+		return PublicPost{}, err
+	}
+	return item.convert()
+}
+
+// 画像などのファイルをダウンロードする
+// 支援者限定のファイルもあるため、セッションを付けて取得する
+func (f PublicFanbox) Download(rawUrl string) ([]byte, error) {
+	return f.fetch(rawUrl)
+}
+
+func (f PublicFanbox) fetch(rawUrl string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Origin", f.defaultParams.origin)
+	req.Header.Set("User-Agent", f.defaultParams.userAgent)
+	if f.sessionId != "" {
+		req.AddCookie(&http.Cookie{Name: "FANBOXSESSID", Value: f.sessionId})
+	}
+
+	res, err := f.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func (f PublicFanbox) get(rawUrl string, v any) error {
+	bytes, err := f.fetch(rawUrl)
+	if err != nil {
+		return err
+	}
+	wrapper := struct {
+		Body json.RawMessage `json:"body"`
+	}{}
+	err = json.Unmarshal(bytes, &wrapper)
+	if err != nil {
+		return err
+	}
+	if wrapper.Body == nil {
+		return errors.New("response has no body")
+	}
+	return json.Unmarshal(wrapper.Body, v)
+}
+
+func (p publicPostApi) convert() (PublicPost, error) {
+	post := fanboxgo.Post{
+		ID:          fanboxgo.NewOptString(p.ID),
+		Title:       fanboxgo.NewOptString(p.Title),
+		Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished), // 公開されている投稿しか見えない
+		FeeRequired: fanboxgo.NewOptInt(p.FeeRequired),
+		UpdatedAt:   fanboxgo.NewOptString(p.UpdatedDatetime),
+		PublishedAt: fanboxgo.NewOptString(p.PublishedDatetime),
+	}
+
+	// 閲覧できない投稿は body が null になる
+	if len(p.Body) > 0 && string(p.Body) != "null" {
+		body := fanboxgo.PostBody{}
+		err := body.UnmarshalJSON(p.Body)
+		if err != nil {
+			return PublicPost{}, err
+		}
+		post.Body = fanboxgo.NewOptPostBody(body)
+	}
+
+	return PublicPost{
+		Post:         post,
+		IsRestricted: p.IsRestricted,
+	}, nil
+}
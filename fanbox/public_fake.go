@@ -0,0 +1,108 @@
+package fanbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+type fakePublicClient struct {
+	creatorId string
+	posts     map[string]PublicPost
+	failures  map[string]bool // post.info を失敗させる投稿の ID
+}
+
+func NewFakePublicHttpClient(creatorId string, posts map[string]PublicPost) *fakePublicClient {
+	return &fakePublicClient{
+		creatorId: creatorId,
+		posts:     posts,
+		failures:  map[string]bool{},
+	}
+}
+
+// 一覧には載るが、本文の取得には失敗する投稿にする
+func (f *fakePublicClient) SetInfoError(postId string) {
+	f.failures[postId] = true
+}
+
+func (f *fakePublicClient) Do(req *http.Request) (*http.Response, error) {
+	var body any
+	switch req.URL.Path {
+	case "/post.paginateCreator":
+		pages := []string{}
+		if req.URL.Query().Get("creatorId") == f.creatorId {
+			pages = append(pages, "https://api.fanbox.cc/post.listCreator?creatorId="+f.creatorId)
+		}
+		body = pages
+	case "/post.listCreator":
+		ids := slices.Collect(maps.Keys(f.posts))
+		sort.Strings(ids)
+		items := []publicPostApi{}
+		for _, id := range ids {
+			item, err := f.toApi(f.posts[id], false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		body = items
+	case "/post.info":
+		postId := req.URL.Query().Get("postId")
+		if f.failures[postId] {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"error":"general_error"}`))),
+			}, nil
+		}
+		post, exist := f.posts[postId]
+		if !exist {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"error":"general_error"}`))),
+			}, nil
+		}
+		item, err := f.toApi(post, true)
+		if err != nil {
+			return nil, err
+		}
+		body = item
+	default:
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(bytes.NewReader([]byte{})),
+		}, nil
+	}
+
+	response, err := json.Marshal(map[string]any{"body": body})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader(response)),
+	}, nil
+}
+
+func (f *fakePublicClient) toApi(post PublicPost, withBody bool) (publicPostApi, error) {
+	item := publicPostApi{
+		ID:                post.Post.ID.Value,
+		Title:             post.Post.Title.Value,
+		FeeRequired:       post.Post.FeeRequired.Value,
+		PublishedDatetime: post.Post.PublishedAt.Value,
+		UpdatedDatetime:   post.Post.UpdatedAt.Value,
+		IsRestricted:      post.IsRestricted,
+		Body:              json.RawMessage("null"),
+	}
+	if withBody && !post.IsRestricted && post.Post.Body.Set {
+		body, err := post.Post.Body.Value.MarshalJSON()
+		if err != nil {
+			return publicPostApi{}, err
+		}
+		item.Body = body
+	}
+	return item, nil
+}
@@ -0,0 +1,141 @@
+package fanbox_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync/fanbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCreatorPosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		creatorId string
+		posts     map[string]PublicPost
+		want      []string
+	}{
+		{
+			name:      "クリエイターの投稿の一覧が取得できる",
+			creatorId: "fanbox",
+			posts: map[string]PublicPost{
+				"1000000": {Post: fanboxgo.Post{ID: fanboxgo.NewOptString("1000000")}},
+				"1000001": {Post: fanboxgo.Post{ID: fanboxgo.NewOptString("1000001")}, IsRestricted: true},
+			},
+			want: []string{"1000000", "1000001"},
+		},
+		{
+			name:      "別のクリエイターの投稿は取得されない",
+			creatorId: "other",
+			posts: map[string]PublicPost{
+				"1000000": {Post: fanboxgo.Post{ID: fanboxgo.NewOptString("1000000")}},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			client := NewFakePublicHttpClient("fanbox", tt.posts)
+			publicFanbox := NewPublicFanbox(client, "", "")
+
+			// execute
+			posts, err := publicFanbox.GetCreatorPosts(tt.creatorId)
+
+			// verify
+			assert.NoError(t, err)
+			ids := []string{}
+			for _, post := range posts {
+				ids = append(ids, post.Post.ID.Value)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestGetCreatorPost(t *testing.T) {
+	body := fanboxgo.PostBody{
+		Blocks: []fanboxgo.PostBodyBlocksItem{
+			{
+				Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+				Text: fanboxgo.NewOptString("本文"),
+			},
+		},
+	}
+	posts := map[string]PublicPost{
+		"1000000": {
+			Post: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Title:       fanboxgo.NewOptString("全体公開の投稿"),
+				FeeRequired: fanboxgo.NewOptInt(0),
+				Body:        fanboxgo.NewOptPostBody(body),
+			},
+		},
+		"1000001": {
+			Post: fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000001"),
+				Title:       fanboxgo.NewOptString("支援者限定の投稿"),
+				FeeRequired: fanboxgo.NewOptInt(500),
+				Body:        fanboxgo.NewOptPostBody(body),
+			},
+			IsRestricted: true,
+		},
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want PublicPost
+	}{
+		{
+			name: "閲覧できる投稿は本文が取得できる",
+			id:   "1000000",
+			want: PublicPost{
+				Post: fanboxgo.Post{
+					ID:          fanboxgo.NewOptString("1000000"),
+					Title:       fanboxgo.NewOptString("全体公開の投稿"),
+					Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+					FeeRequired: fanboxgo.NewOptInt(0),
+					UpdatedAt:   fanboxgo.NewOptString(""),
+					PublishedAt: fanboxgo.NewOptString(""),
+					Body:        fanboxgo.NewOptPostBody(body),
+				},
+			},
+		},
+		{
+			name: "閲覧できない投稿は本文が空になる",
+			id:   "1000001",
+			want: PublicPost{
+				Post: fanboxgo.Post{
+					ID:          fanboxgo.NewOptString("1000001"),
+					Title:       fanboxgo.NewOptString("支援者限定の投稿"),
+					Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+					FeeRequired: fanboxgo.NewOptInt(500),
+					UpdatedAt:   fanboxgo.NewOptString(""),
+					PublishedAt: fanboxgo.NewOptString(""),
+				},
+				IsRestricted: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			client := NewFakePublicHttpClient("fanbox", posts)
+			publicFanbox := NewPublicFanbox(client, "", "")
+
+			// execute
+			post, err := publicFanbox.GetCreatorPost(tt.id)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, post)
+		})
+	}
+}
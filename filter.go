@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// pull の対象を絞り込むための条件
+// 空の項目は条件として扱わない
+type PullFilter struct {
+	ID     string
+	Status string
+	Since  time.Time
+	Until  time.Time
+	Fee    string
+	Tag    string // 一覧にはタグが無いので、Match ではなく MatchTags で確かめる
+}
+
+// 日付だけの指定は、投稿の日時と同じ日本時間の日付として扱う
+var filterLocation = time.FixedZone("JST", 9*60*60)
+
+func NewPullFilter(id, status, since, until, fee, tag string) (*PullFilter, error) {
+	filter := &PullFilter{
+		ID:     id,
+		Status: status,
+		Fee:    fee,
+		Tag:    tag,
+	}
+
+	if status != "" {
+		if err := fanboxgo.PostStatus(status).Validate(); err != nil {
+			return nil, fmt.Errorf("invalid status: %s", status)
+		}
+	}
+	if fee != "" {
+		if _, err := strconv.Atoi(fee); err != nil {
+			return nil, fmt.Errorf("invalid fee: %s", fee)
+		}
+	}
+
+	if since != "" {
+		t, _, err := parseFilterTime(since)
+		if err != nil {
+			return nil, err
+		}
+		filter.Since = t
+	}
+	if until != "" {
+		t, dateOnly, err := parseFilterTime(until)
+		if err != nil {
+			return nil, err
+		}
+		// YYYY-MM-DD で指定された場合はその日の終わりまでを含める
+		if dateOnly {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		filter.Until = t
+	}
+
+	return filter, nil
+}
+
+// YYYY-MM-DD か RFC3339 の形式を受け付ける
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, filterLocation); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date: %s", value)
+	}
+	return t, false, nil
+}
+
+func (p *PullFilter) Match(post fanboxgo.Post) bool {
+	if p.ID != "" && post.ID.Value != p.ID {
+		return false
+	}
+	if p.Status != "" && string(post.Status.Value) != p.Status {
+		return false
+	}
+	if p.Fee != "" && fmt.Sprint(post.FeeRequired.Value) != p.Fee {
+		return false
+	}
+
+	if !p.Since.IsZero() || !p.Until.IsZero() {
+		// 公開されていない投稿には公開日時が無いため、期間指定には含めない
+		publishedAt, err := time.Parse(time.RFC3339, post.PublishedAt.Value)
+		if err != nil {
+			return false
+		}
+		if !p.Since.IsZero() && publishedAt.Before(p.Since) {
+			return false
+		}
+		if !p.Until.IsZero() && publishedAt.After(p.Until) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 投稿のタグが条件に合うか
+// タグは投稿ごとに取得しなければ分からないので、Tag が空でなければ取得してから呼ぶ
+func (p *PullFilter) MatchTags(tags []string) bool {
+	return p.Tag == "" || slices.Contains(tags, p.Tag)
+}
@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullFilterMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		status      string
+		since       string
+		until       string
+		fee         string
+		publishedAt string // 空なら 2024-05-10T12:00:00+09:00
+		want        bool
+	}{
+		{
+			name: "条件が無ければ全て対象になる",
+			want: true,
+		},
+		{
+			name: "ID が一致すれば対象になる",
+			id:   "1000000",
+			want: true,
+		},
+		{
+			name: "ID が異なれば対象外になる",
+			id:   "1000001",
+			want: false,
+		},
+		{
+			name:   "ステータスが異なれば対象外になる",
+			status: "draft",
+			want:   false,
+		},
+		{
+			name: "金額が異なれば対象外になる",
+			fee:  "0",
+			want: false,
+		},
+		{
+			name:  "期間内であれば対象になる",
+			since: "2024-05-01",
+			until: "2024-05-10",
+			want:  true,
+		},
+		{
+			name:  "期間より前であれば対象外になる",
+			since: "2024-05-11",
+			want:  false,
+		},
+		{
+			name:  "期間より後であれば対象外になる",
+			until: "2024-05-10T00:00:00+09:00",
+			want:  false,
+		},
+		{
+			name:        "日付だけなら日本時間の日付で比べる",
+			since:       "2024-05-10",
+			publishedAt: "2024-05-10T00:30:00+09:00",
+			want:        true,
+		},
+		{
+			name:        "日付だけの終わりも日本時間の日付で比べる",
+			until:       "2024-05-09",
+			publishedAt: "2024-05-10T00:30:00+09:00",
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			publishedAt := tt.publishedAt
+			if publishedAt == "" {
+				publishedAt = "2024-05-10T12:00:00+09:00"
+			}
+			post := fanboxgo.Post{
+				ID:          fanboxgo.NewOptString("1000000"),
+				Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+				FeeRequired: fanboxgo.NewOptInt(500),
+				PublishedAt: fanboxgo.NewOptString(publishedAt),
+			}
+			filter, err := NewPullFilter(tt.id, tt.status, tt.since, tt.until, tt.fee, "")
+			assert.NoError(t, err)
+
+			// execute
+			got := filter.Match(post)
+
+			// verify
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPullFilterMatchTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		tags []string
+		want bool
+	}{
+		{
+			name: "タグの条件が無ければ対象になる",
+			tags: nil,
+			want: true,
+		},
+		{
+			name: "タグが付いていれば対象になる",
+			tag:  "イラスト",
+			tags: []string{"漫画", "イラスト"},
+			want: true,
+		},
+		{
+			name: "タグが付いていなければ対象外になる",
+			tag:  "イラスト",
+			tags: []string{"漫画"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			filter, err := NewPullFilter("", "", "", "", "", tt.tag)
+			assert.NoError(t, err)
+
+			// execute
+			got := filter.MatchTags(tt.tags)
+
+			// verify
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
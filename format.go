@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	"github.com/defaultcf/fanboxsync/fanbox"
+)
+
+const (
+	BodyFormatBlocks   = "blocks"
+	BodyFormatAsciiDoc = "asciidoc"
+)
+
+func init() {
+	RegisterBodyFormat(BodyFormatBlocks, blocksFormat{})
+	RegisterBodyFormat(BodyFormatAsciiDoc, asciiDocFormat{}, ".adoc", ".asciidoc")
+}
+
+// post.update に送るブロックの JSON をそのまま本文にする
+// Markdown では書けない本文のための書式
+type blocksFormat struct{}
+
+func (blocksFormat) Render(e *Entry, post *fanboxgo.Post) (string, map[string]string, error) {
+	// 埋め込みの中身は FANBOX 上にあるので、ブロックだけを書き出す
+	bodyJson, err := fanbox.ConvertJson(&fanboxgo.PostBody{Blocks: post.Body.Value.Blocks})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 手で編集しやすいよう、インデントを付けておく
+	var indented bytes.Buffer
+	err = json.Indent(&indented, []byte(bodyJson), "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	return indented.String(), nil, nil
+}
+
+func (blocksFormat) Parse(entry *Entry) (*fanboxgo.PostBody, error) {
+	bodyJson := strings.TrimSpace(entry.Body)
+	if bodyJson == "" {
+		return &fanboxgo.PostBody{}, nil
+	}
+	// ConvertJson が書き出すブロックの配列のほか、本文のオブジェクトも読める
+	if strings.HasPrefix(bodyJson, "[") {
+		bodyJson = fmt.Sprintf(`{"blocks":%s}`, bodyJson)
+	}
+
+	postBody := &fanboxgo.PostBody{}
+	err := postBody.UnmarshalJSON([]byte(bodyJson))
+	if err != nil {
+		return nil, fmt.Errorf("invalid blocks: %w", err)
+	}
+	return postBody, nil
+}
+
+// AsciiDoc の書式
+// 行ごとに Markdown と書き換え、変換は Markdown のものを使う
+type asciiDocFormat struct{}
+
+var (
+	reAsciiDocHeader = regexp.MustCompile(`^== (.+)`)
+	reAsciiDocImage  = regexp.MustCompile(`^image::(.+)\[(.+)\]$`)
+	reAsciiDocLink   = regexp.MustCompile(`^(https?://\S+)\[(.+)\]$`)
+	reAsciiDocBold   = regexp.MustCompile(`\*(.+?)\*`)
+
+	reMarkdownHeader = regexp.MustCompile(`^## (.+)`)
+	// 行の残りを落とさないよう、行全体が画像やリンクのときだけ合わせる
+	reMarkdownImage = regexp.MustCompile(`^!\[(.+)\]\((.+)\)\s*$`)
+	reMarkdownLink  = regexp.MustCompile(`^\[(.+)\]\((.+)\)\s*$`)
+	reMarkdownBold  = regexp.MustCompile(`\*\*(.+?)\*\*`)
+)
+
+func (asciiDocFormat) Render(e *Entry, post *fanboxgo.Post) (string, map[string]string, error) {
+	body, embeds, err := markdownFormat{}.Render(e, post)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if matches := reMarkdownHeader.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("== %s", matches[1])
+		} else if matches := reMarkdownImage.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("image::%s[%s]", matches[2], matches[1])
+		} else if matches := reMarkdownLink.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("%s[%s]", matches[2], matches[1])
+		} else {
+			lines[i] = reMarkdownBold.ReplaceAllString(line, "*$1*")
+		}
+	}
+	return strings.Join(lines, "\n"), embeds, nil
+}
+
+func (asciiDocFormat) Parse(entry *Entry) (*fanboxgo.PostBody, error) {
+	lines := strings.Split(entry.Body, "\n")
+	for i, line := range lines {
+		if matches := reAsciiDocHeader.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("## %s", matches[1])
+		} else if matches := reAsciiDocImage.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("![%s](%s)", matches[2], matches[1])
+		} else if matches := reAsciiDocLink.FindStringSubmatch(line); len(matches) > 0 {
+			lines[i] = fmt.Sprintf("[%s](%s)", matches[2], matches[1])
+		} else {
+			lines[i] = reAsciiDocBold.ReplaceAllString(line, "**$1**")
+		}
+	}
+
+	converted := *entry
+	converted.Body = strings.Join(lines, "\n")
+	return markdownFormat{}.Parse(&converted)
+}
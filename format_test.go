@@ -0,0 +1,177 @@
+package main_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyFormat(t *testing.T) {
+	post := fanboxgo.Post{
+		ID:          fanboxgo.NewOptString("1000000"),
+		Title:       fanboxgo.NewOptString("テスト投稿"),
+		Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+		FeeRequired: fanboxgo.NewOptInt(0),
+		Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+			Blocks: []fanboxgo.PostBodyBlocksItem{
+				{
+					Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeHeader),
+					Text: fanboxgo.NewOptString("タイトル"),
+				},
+				{
+					Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+					Text: fanboxgo.NewOptString("これは太字です"),
+					Styles: []fanboxgo.PostBodyBlocksItemStylesItem{
+						{
+							Type:   fanboxgo.NewOptPostBodyBlocksItemStylesItemType(fanboxgo.PostBodyBlocksItemStylesItemTypeBold),
+							Offset: fanboxgo.NewOptInt(3),
+							Length: fanboxgo.NewOptInt(2),
+						},
+					},
+				},
+				{
+					Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
+					ImageId: fanboxgo.NewOptString("image1"),
+				},
+			},
+			ImageMap: fanboxgo.NewOptPostBodyImageMap(fanboxgo.PostBodyImageMap{
+				"image1": {OriginalUrl: fanboxgo.NewOptString("https://example.com/image1.png")},
+			}),
+		}),
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "Markdown で読み書きできる",
+			format: BodyFormatMarkdown,
+			want:   "## タイトル\nこれは**太字**です\n![image1](https://example.com/image1.png)",
+		},
+		{
+			name:   "AsciiDoc で読み書きできる",
+			format: BodyFormatAsciiDoc,
+			want:   "== タイトル\nこれは*太字*です\nimage::https://example.com/image1.png[image1]",
+		},
+		{
+			name:   "ブロックの JSON のまま読み書きできる",
+			format: BodyFormatBlocks,
+			want: `[
+  {
+    "type": "header",
+    "text": "タイトル"
+  },
+  {
+    "type": "p",
+    "text": "これは太字です",
+    "styles": [
+      {
+        "type": "bold",
+        "offset": 3,
+        "length": 2
+      }
+    ]
+  },
+  {
+    "type": "image",
+    "imageId": "image1"
+  }
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			e := Entry{Format: tt.format}
+
+			// execute
+			converted, err := e.ConvertPost(&post)
+			assert.NoError(t, err)
+			pushed, err := e.ConvertFanbox(converted)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, converted.Body)
+			assert.Equal(t, post.Body.Value.Blocks, pushed.Body.Value.Blocks)
+		})
+	}
+}
+
+func TestBodyFormatFromPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: ".md は markdown になる",
+			path: "2024-01-01-1000000.md",
+			want: BodyFormatMarkdown,
+		},
+		{
+			name: ".adoc は asciidoc になる",
+			path: "posts/2024-01-01-1000000.adoc",
+			want: BodyFormatAsciiDoc,
+		},
+		{
+			name: "知らない拡張子は markdown になる",
+			path: "post.txt",
+			want: BodyFormatMarkdown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			got := BodyFormatFromPath(tt.path)
+
+			// verify
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBodyFormatFileExt(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "markdown は .md",
+			format: BodyFormatMarkdown,
+			want:   ".md",
+		},
+		{
+			name:   "asciidoc は最初に登録した .adoc",
+			format: BodyFormatAsciiDoc,
+			want:   ".adoc",
+		},
+		{
+			name:   "拡張子の無い blocks は .md",
+			format: BodyFormatBlocks,
+			want:   ".md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			got := BodyFormatFileExt(tt.format)
+
+			// verify
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
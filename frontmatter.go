@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/token"
+)
+
+// front matter の区切り
+// --- なら YAML、+++ なら TOML として読む
+const (
+	frontMatterYAML = "---"
+	frontMatterTOML = "+++"
+)
+
+// front matter が読めないときのエラー
+// Line はファイルの先頭から数えた行番号
+type FrontMatterError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *FrontMatterError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+}
+
+// ファイルの内容を front matter と本文に分け、front matter を v に読み込む
+// 本文と、本文が始まる行番号を返す
+// 改行は CRLF でもよく、区切りの後の空行は無くてもよい
+func ParseFrontMatter(path string, content string, v any) (string, int, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	delimiter := strings.TrimRight(lines[0], " \t")
+	if delimiter != frontMatterYAML && delimiter != frontMatterTOML {
+		return "", 0, &FrontMatterError{Path: path, Line: 1, Message: "front matter must start with --- or +++"}
+	}
+	// 本文に区切りと同じ行があっても、最初に閉じたところまでを front matter とする
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t") == delimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", 0, &FrontMatterError{Path: path, Line: 1, Message: fmt.Sprintf("front matter is not closed with %s", delimiter)}
+	}
+
+	frontMatter := strings.Join(lines[1:end], "\n")
+	switch delimiter {
+	case frontMatterYAML:
+		err := yaml.Unmarshal([]byte(frontMatter), v)
+		if err != nil {
+			return "", 0, newYAMLFrontMatterError(path, err)
+		}
+	case frontMatterTOML:
+		_, err := toml.Decode(frontMatter, v)
+		if err != nil {
+			return "", 0, newTOMLFrontMatterError(path, err)
+		}
+	}
+
+	// 区切りの後の空行は本文に含めない
+	bodyStart := end + 1
+	if bodyStart < len(lines) && lines[bodyStart] == "" {
+		bodyStart++
+	}
+	if bodyStart >= len(lines) {
+		return "", len(lines) + 1, nil
+	}
+	return strings.Join(lines[bodyStart:], "\n"), bodyStart + 1, nil
+}
+
+// 行番号は front matter の中でのものなので、開始の区切りの 1 行を足す
+func newYAMLFrontMatterError(path string, err error) error {
+	var tokenErr interface {
+		GetToken() *token.Token
+		GetMessage() string
+	}
+	if errors.As(err, &tokenErr) && tokenErr.GetToken() != nil {
+		return &FrontMatterError{Path: path, Line: tokenErr.GetToken().Position.Line + 1, Message: tokenErr.GetMessage()}
+	}
+	return &FrontMatterError{Path: path, Line: 1, Message: err.Error()}
+}
+
+func newTOMLFrontMatterError(path string, err error) error {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		return &FrontMatterError{Path: path, Line: parseErr.Position.Line + 1, Message: parseErr.Message}
+	}
+	return &FrontMatterError{Path: path, Line: 1, Message: err.Error()}
+}
+
+// v を front matter にする
+// delimiter が +++ なら TOML で書く
+func formatFrontMatter(delimiter string, v any) (string, error) {
+	var b bytes.Buffer
+	b.WriteString(delimiter + "\n")
+	switch delimiter {
+	case frontMatterYAML:
+		metaBytes, err := yaml.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		b.Write(metaBytes)
+	case frontMatterTOML:
+		err := toml.NewEncoder(&b).Encode(v)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("invalid front matter delimiter: %s", delimiter)
+	}
+	b.WriteString(delimiter + "\n")
+	return b.String(), nil
+}
+
+// 既にあるファイルの front matter の区切りを返す
+// ファイルが無いか読めなければ、YAML の区切りを返す
+func existingFrontMatterDelimiter(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return frontMatterYAML
+	}
+	if strings.HasPrefix(strings.TrimPrefix(string(content), "\ufeff"), frontMatterTOML) {
+		return frontMatterTOML
+	}
+	return frontMatterYAML
+}
@@ -0,0 +1,126 @@
+package main_test
+
+import (
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	type frontMatter struct {
+		Title string `yaml:"title" toml:"title"`
+		Fee   string `yaml:"fee" toml:"fee"`
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		want     frontMatter
+		wantBody string
+		wantLine int
+		wantErr  error
+	}{
+		{
+			name:     "YAML の front matter を読める",
+			content:  "---\ntitle: テスト投稿\nfee: \"500\"\n---\n\n本文\n",
+			want:     frontMatter{Title: "テスト投稿", Fee: "500"},
+			wantBody: "本文\n",
+			wantLine: 6,
+		},
+		{
+			name:     "TOML の front matter を読める",
+			content:  "+++\ntitle = \"テスト投稿\"\nfee = \"500\"\n+++\n\n本文\n",
+			want:     frontMatter{Title: "テスト投稿", Fee: "500"},
+			wantBody: "本文\n",
+			wantLine: 6,
+		},
+		{
+			name:     "CRLF でも読める",
+			content:  "---\r\ntitle: テスト投稿\r\n---\r\n\r\n1行目\r\n2行目",
+			want:     frontMatter{Title: "テスト投稿"},
+			wantBody: "1行目\n2行目",
+			wantLine: 5,
+		},
+		{
+			name:     "区切りの後に空行が無くても読める",
+			content:  "---\ntitle: テスト投稿\n---\n本文",
+			want:     frontMatter{Title: "テスト投稿"},
+			wantBody: "本文",
+			wantLine: 4,
+		},
+		{
+			name:     "本文の --- は本文として残す",
+			content:  "---\ntitle: テスト投稿\n---\n\n上\n---\n\n下",
+			want:     frontMatter{Title: "テスト投稿"},
+			wantBody: "上\n---\n\n下",
+			wantLine: 5,
+		},
+		{
+			name:     "本文が無くても読める",
+			content:  "---\ntitle: テスト投稿\n---",
+			want:     frontMatter{Title: "テスト投稿"},
+			wantBody: "",
+			wantLine: 4,
+		},
+		{
+			name:    "front matter が無ければエラーにする",
+			content: "本文だけ",
+			wantErr: &FrontMatterError{Path: "post.md", Line: 1, Message: "front matter must start with --- or +++"},
+		},
+		{
+			name:    "閉じていなければエラーにする",
+			content: "---\ntitle: テスト投稿\n\n本文",
+			wantErr: &FrontMatterError{Path: "post.md", Line: 1, Message: "front matter is not closed with ---"},
+		},
+		{
+			name:    "空のファイルはエラーにする",
+			content: "",
+			wantErr: &FrontMatterError{Path: "post.md", Line: 1, Message: "front matter must start with --- or +++"},
+		},
+		{
+			name:    "TOML の誤りは行番号を付けて返す",
+			content: "+++\ntitle = \"テスト投稿\"\nfee = \n+++\n",
+			wantErr: &FrontMatterError{Path: "post.md", Line: 3, Message: "unexpected EOF; expected value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			got := frontMatter{}
+
+			// execute
+			body, line, err := ParseFrontMatter("post.md", tt.content, &got)
+
+			// verify
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantBody, body)
+			assert.Equal(t, tt.wantLine, line)
+		})
+	}
+}
+
+func TestParseFrontMatterYAMLError(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	got := struct {
+		Title string `yaml:"title"`
+	}{}
+
+	// execute
+	_, _, err := ParseFrontMatter("post.md", "---\ntitle: テスト投稿\nfee: [\n---\n", &got)
+
+	// verify
+	var frontMatterErr *FrontMatterError
+	assert.ErrorAs(t, err, &frontMatterErr)
+	assert.Equal(t, 3, frontMatterErr.Line)
+}
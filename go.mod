@@ -5,7 +5,9 @@ go 1.25.0
 toolchain go1.26.4
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/defaultcf/fanbox-go v1.2.1
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/goccy/go-yaml v1.19.2
 	github.com/stretchr/testify v1.11.1
 	github.com/urfave/cli/v2 v2.27.7
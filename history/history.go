@@ -0,0 +1,99 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// push する前のサーバー上の投稿を、投稿ごとに日時付きで保存する
+// <dir>/<投稿の ID>/<リビジョン>.json の形で置く
+type Store struct {
+	dir string
+}
+
+// リビジョンは保存した日時で、辞書順に並べると古い順になる
+const revisionFormat = "20060102T150405.000000000Z"
+
+var ErrNotFound = errors.New("revision is not found")
+
+func NewStore(dir string) *Store {
+	return &Store{
+		dir: dir,
+	}
+}
+
+// 投稿を保存し、そのリビジョンを返す
+func (s *Store) Save(post fanboxgo.Post) (string, error) {
+	if post.ID.Value == "" {
+		return "", errors.New("post id is empty")
+	}
+	postDir := filepath.Join(s.dir, post.ID.Value)
+	err := os.MkdirAll(postDir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := post.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	revision := time.Now().UTC().Format(revisionFormat)
+	err = os.WriteFile(filepath.Join(postDir, revision+".json"), bytes, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return revision, nil
+}
+
+// 投稿のリビジョンを古い順に返す
+func (s *Store) List(postId string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, postId))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		revisions = append(revisions, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(revisions)
+
+	return revisions, nil
+}
+
+func (s *Store) Load(postId string, revision string) (fanboxgo.Post, error) {
+	// 別の投稿のファイルを読まないよう、リビジョンにパスが含まれていれば弾く
+	if filepath.Base(revision) != revision {
+		return fanboxgo.Post{}, fmt.Errorf("invalid revision: %s", revision)
+	}
+
+	bytes, err := os.ReadFile(filepath.Join(s.dir, postId, revision+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return fanboxgo.Post{}, ErrNotFound
+	}
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+
+	post := fanboxgo.Post{}
+	err = post.UnmarshalJSON(bytes)
+	if err != nil {
+		return fanboxgo.Post{}, err
+	}
+	return post, nil
+}
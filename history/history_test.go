@@ -0,0 +1,77 @@
+package history_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		posts []fanboxgo.Post
+		id    string
+		want  []fanboxgo.Post
+	}{
+		{
+			name: "保存した投稿を古い順に取り出せる",
+			posts: []fanboxgo.Post{
+				{ID: fanboxgo.NewOptString("1000000"), Title: fanboxgo.NewOptString("1回目")},
+				{ID: fanboxgo.NewOptString("1000001"), Title: fanboxgo.NewOptString("別の投稿")},
+				{ID: fanboxgo.NewOptString("1000000"), Title: fanboxgo.NewOptString("2回目")},
+			},
+			id: "1000000",
+			want: []fanboxgo.Post{
+				{ID: fanboxgo.NewOptString("1000000"), Title: fanboxgo.NewOptString("1回目")},
+				{ID: fanboxgo.NewOptString("1000000"), Title: fanboxgo.NewOptString("2回目")},
+			},
+		},
+		{
+			name:  "保存されていなければ空になる",
+			posts: []fanboxgo.Post{},
+			id:    "1000000",
+			want:  []fanboxgo.Post{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			store := NewStore(t.TempDir())
+			for _, post := range tt.posts {
+				_, err := store.Save(post)
+				assert.NoError(t, err)
+			}
+
+			// execute
+			revisions, err := store.List(tt.id)
+
+			// verify
+			assert.NoError(t, err)
+			posts := []fanboxgo.Post{}
+			for _, revision := range revisions {
+				post, err := store.Load(tt.id, revision)
+				assert.NoError(t, err)
+				posts = append(posts, post)
+			}
+			assert.Equal(t, tt.want, posts)
+		})
+	}
+}
+
+func TestStoreLoadNotFound(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	store := NewStore(t.TempDir())
+
+	// execute
+	_, err := store.Load("1000000", "20240101T000000.000000000Z")
+
+	// verify
+	assert.ErrorIs(t, err, ErrNotFound)
+}
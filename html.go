@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// Fanbox のブロックから HTML の本文を作る
+// images には画像の ID ごとの参照先を渡し、無ければ FANBOX 上の URL を使う
+// EPUB でも使えるよう、XHTML として正しい形で書き出す
+func (e *Entry) ConvertHTML(post *fanboxgo.Post, images map[string]string) (string, error) {
+	body := []string{fmt.Sprintf("<h1>%s</h1>", html.EscapeString(post.Title.Value))}
+	for _, block := range post.Body.Value.Blocks {
+		switch t, _ := block.Type.Get(); t {
+		case fanboxgo.PostBodyBlocksItemTypeP:
+			processedText, err := renderStyledText(block, "<strong>", "</strong>", html.EscapeString)
+			if err != nil {
+				return "", err
+			}
+			body = append(body, fmt.Sprintf("<p>%s</p>", processedText))
+		case fanboxgo.PostBodyBlocksItemTypeHeader:
+			body = append(body, fmt.Sprintf("<h2>%s</h2>", html.EscapeString(block.Text.Value)))
+		case fanboxgo.PostBodyBlocksItemTypeImage:
+			src, exist := images[block.ImageId.Value]
+			if !exist {
+				src = post.Body.Value.ImageMap.Value[block.ImageId.Value].OriginalUrl.Value
+			}
+			body = append(body, fmt.Sprintf(`<figure><img src="%s" alt="%s"/></figure>`, html.EscapeString(src), html.EscapeString(block.ImageId.Value)))
+		case fanboxgo.PostBodyBlocksItemTypeURLEmbed:
+			embed := post.Body.Value.UrlEmbedMap.Value[block.UrlEmbedId.Value]
+			url, err := e.getEmbedUrl(embed.Type.Value, embed)
+			if err != nil {
+				return "", err
+			}
+			body = append(body, fmt.Sprintf(`<p class="embed"><a href="%s">%s</a></p>`, html.EscapeString(url), html.EscapeString(url)))
+		}
+	}
+
+	return strings.Join(body, "\n"), nil
+}
+
+// 本文を 1 つの XHTML の文書にする
+func HTMLPage(title string, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="ja">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+</head>
+<body>
+<article>
+%s
+</article>
+</body>
+</html>
+`, html.EscapeString(title), body)
+}
@@ -0,0 +1,80 @@
+package main_test
+
+import (
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   fanboxgo.Post
+		images map[string]string
+		want   string
+	}{
+		{
+			name: "FANBOX から HTML に変換できる",
+			post: fanboxgo.Post{
+				Title: fanboxgo.NewOptString("テスト<投稿>"),
+				Body: fanboxgo.NewOptPostBody(fanboxgo.PostBody{
+					Blocks: []fanboxgo.PostBodyBlocksItem{
+						{
+							Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeHeader),
+							Text: fanboxgo.NewOptString("見出し"),
+						},
+						{
+							Type: fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeP),
+							Text: fanboxgo.NewOptString("これは太字です & <タグ>"),
+							Styles: []fanboxgo.PostBodyBlocksItemStylesItem{
+								{
+									Type:   fanboxgo.NewOptPostBodyBlocksItemStylesItemType(fanboxgo.PostBodyBlocksItemStylesItemTypeBold),
+									Offset: fanboxgo.NewOptInt(3),
+									Length: fanboxgo.NewOptInt(2),
+								},
+							},
+						},
+						{
+							Type:    fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeImage),
+							ImageId: fanboxgo.NewOptString("img"),
+						},
+						{
+							Type:       fanboxgo.NewOptPostBodyBlocksItemType(fanboxgo.PostBodyBlocksItemTypeURLEmbed),
+							UrlEmbedId: fanboxgo.NewOptString("embed"),
+						},
+					},
+					UrlEmbedMap: fanboxgo.NewOptPostBodyUrlEmbedMap(fanboxgo.PostBodyUrlEmbedMap{
+						"embed": {
+							Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeDefault),
+							URL:  fanboxgo.NewOptString("https://example.com/?a=1&b=2"),
+						},
+					}),
+				}),
+			},
+			images: map[string]string{"img": "images/img.png"},
+			want: "<h1>テスト&lt;投稿&gt;</h1>\n" +
+				"<h2>見出し</h2>\n" +
+				"<p>これは<strong>太字</strong>です &amp; &lt;タグ&gt;</p>\n" +
+				`<figure><img src="images/img.png" alt="img"/></figure>` + "\n" +
+				`<p class="embed"><a href="https://example.com/?a=1&amp;b=2">https://example.com/?a=1&amp;b=2</a></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			e := Entry{}
+
+			// execute
+			body, err := e.ConvertHTML(&tt.post, tt.images)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, body)
+		})
+	}
+}
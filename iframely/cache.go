@@ -0,0 +1,60 @@
+package iframely
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// iframely の ID ごとに、解決した URL をファイルに保存する
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+type cacheEntry struct {
+	Url       string    `json:"url"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{
+		dir: dir,
+		ttl: ttl,
+	}
+}
+
+// キャッシュされた URL と、それが有効期限内かどうかを返す
+func (c *Cache) Get(iframelyId string) (string, bool, bool) {
+	bytes, err := os.ReadFile(c.path(iframelyId))
+	if err != nil {
+		return "", false, false
+	}
+	entry := cacheEntry{}
+	err = json.Unmarshal(bytes, &entry)
+	if err != nil {
+		return "", false, false
+	}
+	fresh := time.Since(entry.FetchedAt) < c.ttl
+	return entry.Url, true, fresh
+}
+
+func (c *Cache) Set(iframelyId string, url string) error {
+	err := os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return err
+	}
+	bytes, err := json.Marshal(&cacheEntry{
+		Url:       url,
+		FetchedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(iframelyId), bytes, 0644)
+}
+
+func (c *Cache) path(iframelyId string) string {
+	return filepath.Join(c.dir, iframelyId+".json")
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"regexp"
 )
@@ -15,8 +16,12 @@ type httpClient interface {
 
 type IframelyClient struct {
 	HttpClient httpClient
+	Cache      *Cache // nil ならキャッシュしない
+	Offline    bool   // true ならキャッシュだけを使い、通信しない
 }
 
+var ErrOffline = errors.New("iframely url is not cached and offline mode is enabled")
+
 type iframelyApi struct {
 	Id  string
 	Url string
@@ -38,13 +43,58 @@ func (c *IframelyClient) GetRealUrl(iframelyUrl string) (string, error) {
 	}
 	iframelyId := matches[0][1]
 
+	var cachedUrl string
+	var cached, fresh bool
+	if c.Cache != nil {
+		cachedUrl, cached, fresh = c.Cache.Get(iframelyId)
+	}
+	// オフラインなら期限切れのキャッシュでも使う
+	if cached && (fresh || c.Offline) {
+		return cachedUrl, nil
+	}
+	if c.Offline {
+		return "", ErrOffline
+	}
+
+	url, err := c.fetch(iframelyId)
+	if err != nil {
+		// 取得できなければ、期限切れでもキャッシュの URL を使う
+		if cached {
+			slog.Warn("failed to fetch iframely url, using expired cache", "id", iframelyId, "error", err)
+			return cachedUrl, nil
+		}
+		return "", err
+	}
+
+	// キャッシュに書けなくても、解決した URL は使える
+	if c.Cache != nil {
+		err = c.Cache.Set(iframelyId, url)
+		if err != nil {
+			slog.Warn("failed to cache iframely url", "id", iframelyId, "error", err)
+		}
+	}
+
+	return url, nil
+}
+
+func (c *IframelyClient) fetch(iframelyId string) (string, error) {
 	response, err := c.HttpClient.Get(fmt.Sprintf("https://cdn.iframe.ly/%s.json", iframelyId))
 	if err != nil {
 		return "", err
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	}
 
-	return c.parseIframely(response.Body)
+	url, err := c.parseIframely(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if url == "" {
+		return "", errors.New("iframely response has no url")
+	}
+	return url, nil
 }
 
 func (c *IframelyClient) parseIframely(data io.Reader) (string, error) {
@@ -9,6 +9,7 @@ import (
 
 type fakeClient struct {
 	httpClient httpClient
+	err        error
 }
 
 func NewFakeHttpClient() *fakeClient {
@@ -17,7 +18,18 @@ func NewFakeHttpClient() *fakeClient {
 	}
 }
 
+// 通信に失敗するクライアント
+func NewFailingFakeHttpClient(err error) *fakeClient {
+	return &fakeClient{
+		err: err,
+	}
+}
+
 func (f *fakeClient) Get(url string) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
 	iframelyResponse, _ := json.Marshal(&iframelyApi{
 		Id:  "123456",
 		Url: "https://example.com/",
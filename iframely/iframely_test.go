@@ -1,7 +1,11 @@
 package iframely_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/defaultcf/fanboxsync/iframely"
 	"github.com/stretchr/testify/assert"
@@ -39,3 +43,119 @@ func TestClient_GetReal(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetRealUrlWithCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		iframelyUrl string
+		cached      bool
+		expired     bool
+		offline     bool
+		fetchErr    error
+		want        string
+		wantErr     error
+	}{
+		{
+			name:        "オンラインなら取得した URL がキャッシュされる",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      false,
+			offline:     false,
+			want:        "https://example.com/",
+		},
+		{
+			name:        "オフラインでもキャッシュがあれば URL を取得できる",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      true,
+			offline:     true,
+			want:        "https://example.com/cached",
+		},
+		{
+			name:        "オフラインでキャッシュが無ければエラーが返る",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      false,
+			offline:     true,
+			want:        "",
+			wantErr:     ErrOffline,
+		},
+		{
+			name:        "期限切れなら取得し直す",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      true,
+			expired:     true,
+			want:        "https://example.com/",
+		},
+		{
+			name:        "取得できなければ期限切れのキャッシュを使う",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      true,
+			expired:     true,
+			fetchErr:    errFetch,
+			want:        "https://example.com/cached",
+		},
+		{
+			name:        "取得できずキャッシュも無ければエラーが返る",
+			iframelyUrl: "https://cdn.iframe.ly/123.json",
+			cached:      false,
+			fetchErr:    errFetch,
+			want:        "",
+			wantErr:     errFetch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			ttl := time.Hour
+			if tt.expired {
+				ttl = -time.Hour
+			}
+			cache := NewCache(t.TempDir(), ttl)
+			if tt.cached {
+				assert.NoError(t, cache.Set("123", "https://example.com/cached"))
+			}
+			httpClient := NewFakeHttpClient()
+			if tt.fetchErr != nil {
+				httpClient = NewFailingFakeHttpClient(tt.fetchErr)
+			}
+			iframelyClient := NewIframelyClient(httpClient)
+			iframelyClient.Cache = cache
+			iframelyClient.Offline = tt.offline
+
+			// execute
+			url, err := iframelyClient.GetRealUrl(tt.iframelyUrl)
+
+			// verify
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, url)
+			if tt.wantErr == nil {
+				cachedUrl, cached, _ := cache.Get("123")
+				assert.True(t, cached)
+				assert.Equal(t, tt.want, cachedUrl)
+			}
+		})
+	}
+}
+
+var errFetch = errors.New("network is unreachable")
+
+func TestClient_GetRealUrlCacheNotWritable(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	// ディレクトリを作るはずの場所にファイルがあれば、キャッシュに書けない
+	dir := filepath.Join(t.TempDir(), "cache")
+	assert.NoError(t, os.WriteFile(dir, []byte{}, 0644))
+	iframelyClient := NewIframelyClient(NewFakeHttpClient())
+	iframelyClient.Cache = NewCache(dir, time.Hour)
+
+	// execute
+	url, err := iframelyClient.GetRealUrl("https://cdn.iframe.ly/123.json")
+
+	// verify
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", url)
+}
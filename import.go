@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 他のサービスから書き出した投稿
+type ImportedPost struct {
+	SourceID string
+	Entry    *Entry
+}
+
+// Patreon の CSV と Ci-en の書き出しは、本文がどう入っているか確かめられないので読まない
+// Ci-en の投稿は、ページを HTML として保存すれば html で読める
+type ImportFormat string
+
+const (
+	ImportFormatHTML    ImportFormat = "html"    // HTML ファイルを置いたディレクトリ
+	ImportFormatNote    ImportFormat = "note"    // note の書き出し (WordPress の WXR 形式)
+	ImportFormatPatreon ImportFormat = "patreon" // Patreon の API の posts の JSON
+)
+
+// 書き出したファイルを読み込み、下書きの Entry にする
+func ImportPosts(format ImportFormat, path string) ([]ImportedPost, error) {
+	switch format {
+	case ImportFormatHTML:
+		return importHTMLDir(path)
+	case ImportFormatNote:
+		return importNote(path)
+	case ImportFormatPatreon:
+		return importPatreon(path)
+	default:
+		return nil, fmt.Errorf("invalid import format: %s", format)
+	}
+}
+
+func newImportedEntry(title string, rawHtml string) (*Entry, error) {
+	body, err := htmlToBody(rawHtml)
+	if err != nil {
+		return nil, err
+	}
+	return NewEntry("", title, string(fanboxgo.PostStatusDraft), "0", body), nil
+}
+
+func importHTMLDir(dir string) ([]ImportedPost, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	posts := []ImportedPost{}
+	for _, path := range paths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		node, err := html.Parse(strings.NewReader(string(bytes)))
+		if err != nil {
+			return nil, err
+		}
+
+		// <title> が無ければ最初の <h1> を、それも無ければファイル名をタイトルにする
+		title := strings.TrimSpace(textContent(findNode(node, "title")))
+		if title == "" {
+			title = strings.TrimSpace(textContent(findNode(node, "h1")))
+		}
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(path), ".html")
+		}
+
+		content := node
+		if body := findNode(node, "body"); body != nil {
+			content = body
+		}
+		entry, err := newImportedEntry(title, renderNode(content))
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, ImportedPost{SourceID: filepath.Base(path), Entry: entry})
+	}
+
+	return posts, nil
+}
+
+type noteRss struct {
+	Items []struct {
+		Title   string `xml:"title"`
+		Link    string `xml:"link"`
+		PostId  string `xml:"http://wordpress.org/export/1.2/ post_id"`
+		Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	} `xml:"channel>item"`
+}
+
+func importNote(path string) ([]ImportedPost, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rss := noteRss{}
+	err = xml.Unmarshal(bytes, &rss)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []ImportedPost{}
+	for _, item := range rss.Items {
+		entry, err := newImportedEntry(item.Title, item.Content)
+		if err != nil {
+			return nil, err
+		}
+		sourceId := item.PostId
+		if sourceId == "" {
+			sourceId = item.Link
+		}
+		posts = append(posts, ImportedPost{SourceID: sourceId, Entry: entry})
+	}
+
+	return posts, nil
+}
+
+type patreonPosts struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func importPatreon(path string) ([]ImportedPost, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data := patreonPosts{}
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []ImportedPost{}
+	for _, item := range data.Data {
+		entry, err := newImportedEntry(item.Attributes.Title, item.Attributes.Content)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, ImportedPost{SourceID: item.ID, Entry: entry})
+	}
+
+	return posts, nil
+}
+
+// HTML を Entry の本文の形式にする
+// 見出しは ## に、太字は ** に、画像は 1 行の画像にし、リンクは文の中に URL を書き添える
+// fanbox-go には画像をアップロードする API が無いため、画像の行は FANBOX でアップロードして ID に書き換えるまで push できない
+func htmlToBody(rawHtml string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(rawHtml), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	var inline strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(inline.String())
+		if text != "" {
+			lines = append(lines, text)
+		}
+		inline.Reset()
+	}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			inline.WriteString(strings.ReplaceAll(node.Data, "\n", " "))
+			return
+		case html.ElementNode:
+		default:
+			return
+		}
+
+		switch node.Data {
+		case "script", "style", "title", "head":
+			return
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			flush()
+			lines = append(lines, fmt.Sprintf("## %s", strings.TrimSpace(textContent(node))))
+			return
+		case "strong", "b":
+			text := textContent(node)
+			if strings.TrimSpace(text) != "" {
+				inline.WriteString(fmt.Sprintf("**%s**", text))
+			}
+			return
+		case "img":
+			flush()
+			src := getAttr(node, "src")
+			if src == "" {
+				return
+			}
+			// 代替テキストが無ければ、ファイル名を画像の名前にする
+			alt := strings.TrimSpace(getAttr(node, "alt"))
+			if alt == "" {
+				alt = path.Base(strings.SplitN(src, "?", 2)[0])
+			}
+			slog.Warn("image must be uploaded on FANBOX and replaced with its image id before push", "src", src)
+			lines = append(lines, fmt.Sprintf("![%s](%s)", alt, src))
+			return
+		case "a":
+			href := getAttr(node, "href")
+			text := textContent(node)
+			if href == "" || strings.TrimSpace(text) == href {
+				inline.WriteString(text)
+			} else {
+				inline.WriteString(fmt.Sprintf("%s (%s)", text, href))
+			}
+			return
+		case "br":
+			flush()
+			return
+		}
+
+		block := node.Data == "p" || node.Data == "div" || node.Data == "li" || node.Data == "blockquote" || node.Data == "figure"
+		if block {
+			flush()
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			flush()
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+	flush()
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func findNode(node *html.Node, tag string) *html.Node {
+	if node.Type == html.ElementNode && node.Data == tag {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findNode(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func renderNode(node *html.Node) string {
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		_ = html.Render(&b, child)
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportPosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ImportFormat
+		files  map[string]string
+		path   string
+		want   []struct {
+			sourceId string
+			title    string
+			body     string
+		}
+	}{
+		{
+			name:   "HTML のファイルを読み込める",
+			format: ImportFormatHTML,
+			files: map[string]string{
+				"a.html": `<html><head><title>HTML の投稿</title></head><body><h2>見出し</h2><p>これは<strong>太字</strong>です</p><p><img src="https://example.com/a.png"></p></body></html>`,
+			},
+			path: ".",
+			want: []struct {
+				sourceId string
+				title    string
+				body     string
+			}{
+				{"a.html", "HTML の投稿", "## 見出し\nこれは**太字**です\n![a.png](https://example.com/a.png)"},
+			},
+		},
+		{
+			name:   "note の書き出しを読み込める",
+			format: ImportFormatNote,
+			files: map[string]string{
+				"note.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+<item>
+<title>note の投稿</title>
+<link>https://note.com/user/n/n123</link>
+<wp:post_id>123</wp:post_id>
+<content:encoded><![CDATA[<p>本文<a href="https://example.com/">リンク</a></p>]]></content:encoded>
+</item>
+</channel>
+</rss>`,
+			},
+			path: "note.xml",
+			want: []struct {
+				sourceId string
+				title    string
+				body     string
+			}{
+				{"123", "note の投稿", "本文リンク (https://example.com/)"},
+			},
+		},
+		{
+			name:   "Patreon の JSON を読み込める",
+			format: ImportFormatPatreon,
+			files: map[string]string{
+				"posts.json": `{"data":[{"id":"456","attributes":{"title":"Patreon の投稿","content":"<p>1行目<br>2行目</p>"}}]}`,
+			},
+			path: "posts.json",
+			want: []struct {
+				sourceId string
+				title    string
+				body     string
+			}{
+				{"456", "Patreon の投稿", "1行目\n2行目"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+			}
+
+			// execute
+			posts, err := ImportPosts(tt.format, filepath.Join(dir, tt.path))
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.want), len(posts))
+			for i, want := range tt.want {
+				assert.Equal(t, want.sourceId, posts[i].SourceID)
+				assert.Equal(t, want.title, posts[i].Entry.Title)
+				assert.Equal(t, want.body, posts[i].Entry.Body)
+				assert.Equal(t, "draft", string(posts[i].Entry.Status))
+			}
+		})
+	}
+}
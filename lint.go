@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// FANBOX で付けられるタイトルの長さの上限
+const maxTitleLength = 100
+
+// lint で見つかった問題
+// Line は行が分かるときだけ付け、ファイル全体の問題なら 0 にする
+type LintIssue struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: [%s] %s", i.Path, i.Line, i.Rule, i.Message)
+	}
+	return fmt.Sprintf("%s: [%s] %s", i.Path, i.Rule, i.Message)
+}
+
+type LintOptions struct {
+	Plans []int // 空でなければ、有料の投稿の価格がこのどれかと一致するか確かめる
+	// nil でなければ、投稿の ID から FANBOX 上の画像の ID を取得し、本文の画像がそこにあるか確かめる
+	RemoteImages func(postId string) (map[string]bool, error)
+}
+
+// Markdown のうち、FANBOX のブロックにできない書き方
+var unsupportedMarkdown = []struct {
+	re      *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`^(#|#{3,6}) `), "only ## headings are supported"},
+	{regexp.MustCompile(`^\s*([-*+]|\d+\.) `), "lists are not supported"},
+	{regexp.MustCompile(`^>`), "blockquotes are not supported"},
+	{regexp.MustCompile("^```"), "code blocks are not supported"},
+	{regexp.MustCompile(`^\|.*\|\s*$`), "tables are not supported"},
+	{regexp.MustCompile(`!?\[[^\]]+\]\([^)]+\)`), "links and images must be on their own line"},
+	{regexp.MustCompile(`(^|[^*])\*[^*\s][^*]*\*([^*]|$)`), "only **bold** is supported"},
+}
+
+// ファイルやディレクトリを確認する
+// ディレクトリなら、本文の書式として登録された拡張子のファイルを再帰的に探す
+func LintFiles(paths []string, opts LintOptions) ([]LintIssue, error) {
+	files, err := findPostFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []LintIssue{}
+	idPaths := map[string][]string{}
+	for _, path := range files {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fileIssues, id, err := LintFile(path, string(bytes), opts)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, fileIssues...)
+		if id != "" {
+			idPaths[id] = append(idPaths[id], path)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(idPaths)) {
+		paths := idPaths[id]
+		for _, path := range paths[1:] {
+			issues = append(issues, LintIssue{
+				Path:    path,
+				Rule:    "duplicate-id",
+				Message: fmt.Sprintf("id %s is also used in %s", id, paths[0]),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// 1 つのファイルを確認し、見つかった問題と投稿の ID を返す
+// 問題があってもエラーにはせず、RemoteImages が失敗したときだけエラーを返す
+func LintFile(path string, content string, opts LintOptions) ([]LintIssue, string, error) {
+	issues := []LintIssue{}
+	add := func(line int, rule string, format string, args ...any) {
+		issues = append(issues, LintIssue{Path: path, Line: line, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	m, body, bodyLine, err := parseFile(path, content)
+	if err != nil {
+		var frontMatterErr *FrontMatterError
+		if errors.As(err, &frontMatterErr) {
+			add(frontMatterErr.Line, "front-matter", "%s", frontMatterErr.Message)
+		} else {
+			add(0, "front-matter", "%s", err)
+		}
+		return issues, "", nil
+	}
+	// 本文の行番号をファイルの行番号にする
+	offset := bodyLine - 1
+
+	if m.Title == "" {
+		add(0, "required", "title is empty")
+	} else if length := len([]rune(m.Title)); length > maxTitleLength {
+		add(0, "title-length", "title is %d characters, longer than %d", length, maxTitleLength)
+	}
+
+	switch fanboxgo.PostStatus(m.Status) {
+	case fanboxgo.PostStatusDraft, fanboxgo.PostStatusPublished:
+	case "":
+		add(0, "required", "status is empty")
+	default:
+		add(0, "status", "status must be draft or published, got %q", m.Status)
+	}
+
+	if m.Fee == "" {
+		add(0, "required", "fee is empty")
+	} else if fee, err := strconv.Atoi(m.Fee); err != nil || fee < 0 {
+		add(0, "fee", "fee must be a non-negative integer, got %q", m.Fee)
+	} else if fee > 0 && len(opts.Plans) > 0 && !slices.Contains(opts.Plans, fee) {
+		add(0, "plan", "fee %d does not match any plan %v", fee, opts.Plans)
+	}
+
+	entry := newEntryFromMeta(m, body)
+	format, err := LookupBodyFormat(entry.Format)
+	if err != nil {
+		add(0, "format", "%s", err)
+		return issues, m.Id, nil
+	}
+	postBody, err := format.Parse(entry)
+	if err != nil {
+		add(0, "format", "%s", err)
+		return issues, m.Id, nil
+	}
+
+	if entry.Format == BodyFormatMarkdown {
+		for i, line := range strings.Split(body, "\n") {
+			// 行全体が画像や埋め込みなら、そのままブロックになる
+			if reMarkdownImage.MatchString(line) || reMarkdownLink.MatchString(line) {
+				continue
+			}
+			for _, rule := range unsupportedMarkdown {
+				if rule.re.MatchString(line) {
+					add(offset+i+1, "markdown", "%s", rule.message)
+				}
+			}
+		}
+	}
+
+	// Markdown と AsciiDoc は 1 行が 1 ブロックになるので、ブロックの位置を行番号にする
+	if entry.Format == BodyFormatMarkdown || entry.Format == BodyFormatAsciiDoc {
+		for i, block := range postBody.Blocks {
+			line := offset + i + 1
+			switch block.Type.Value {
+			case fanboxgo.PostBodyBlocksItemTypeImage:
+				src := postBody.ImageMap.Value[block.ImageId.Value].OriginalUrl.Value
+				// URL でなければ、ファイルからの相対パスとして扱う
+				if u, err := url.Parse(src); err == nil && u.Scheme != "" {
+					continue
+				}
+				imagePath := filepath.Join(filepath.Dir(path), src)
+				if _, err := os.Stat(imagePath); err != nil {
+					add(line, "image", "%s does not exist", imagePath)
+				}
+				add(line, "local-image", "%s cannot be uploaded, push skips it", src)
+			case fanboxgo.PostBodyBlocksItemTypeP:
+				// 埋め込みにならなかったリンクは、書いたまま段落になる
+				if matches := reMarkdownLink.FindStringSubmatch(block.Text.Value); len(matches) > 0 {
+					add(line, "embed", "link %s is not a known embed, push sends it as text", matches[1])
+				}
+			}
+		}
+	}
+
+	if opts.RemoteImages != nil && m.Id != "" {
+		images, err := opts.RemoteImages(m.Id)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, block := range postBody.Blocks {
+			if block.Type.Value == fanboxgo.PostBodyBlocksItemTypeImage && !images[block.ImageId.Value] {
+				add(0, "image", "image %s is not uploaded to post %s", block.ImageId.Value, m.Id)
+			}
+		}
+	}
+
+	return issues, m.Id, nil
+}
+
+func findPostFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != path && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, exist := bodyFormatExts[strings.ToLower(filepath.Ext(p))]; exist {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
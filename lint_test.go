@@ -0,0 +1,122 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    LintOptions
+		want    []LintIssue
+	}{
+		{
+			name:    "問題が無ければ何も返さない",
+			content: "---\nid: \"1\"\ntitle: テスト投稿\nstatus: draft\nfee: \"500\"\nembeds:\n  abc: https://example.com\n---\n\n## 見出し\nこれは**太字**です\n[abc](https://example.com)\n[前回](https://creator.fanbox.cc/posts/123)\n",
+			opts:    LintOptions{Plans: []int{500}},
+			want:    []LintIssue{},
+		},
+		{
+			name:    "front matter が無ければ問題にする",
+			content: "本文だけ",
+			want: []LintIssue{
+				{Path: "post.md", Line: 1, Rule: "front-matter", Message: "front matter must start with --- or +++"},
+			},
+		},
+		{
+			name:    "front matter の値を確かめる",
+			content: "---\ntitle: \"\"\nstatus: reserved\nfee: \"300\"\n---\n\n本文\n",
+			opts:    LintOptions{Plans: []int{500}},
+			want: []LintIssue{
+				{Path: "post.md", Rule: "required", Message: "title is empty"},
+				{Path: "post.md", Rule: "status", Message: `status must be draft or published, got "reserved"`},
+				{Path: "post.md", Rule: "plan", Message: "fee 300 does not match any plan [500]"},
+			},
+		},
+		{
+			name:    "FANBOX で使えない Markdown を行番号と一緒に返す",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\n# 見出し\n- リスト\nこれは*斜体*です\n",
+			want: []LintIssue{
+				{Path: "post.md", Line: 7, Rule: "markdown", Message: "only ## headings are supported"},
+				{Path: "post.md", Line: 8, Rule: "markdown", Message: "lists are not supported"},
+				{Path: "post.md", Line: 9, Rule: "markdown", Message: "only **bold** is supported"},
+			},
+		},
+		{
+			name:    "FANBOX にアップロードされていない画像を返す",
+			content: "---\nid: \"1\"\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\n![image1](https://example.com/1.png)\n![image2](https://example.com/2.png)\n",
+			opts: LintOptions{
+				RemoteImages: func(postId string) (map[string]bool, error) {
+					return map[string]bool{"image1": true}, nil
+				},
+			},
+			want: []LintIssue{
+				{Path: "post.md", Rule: "image", Message: "image image2 is not uploaded to post 1"},
+			},
+		},
+		{
+			name:    "埋め込みにならないリンクと、手元の画像を返す",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\n[リンク](https://example.com)\n![missing](missing.png)\n",
+			want: []LintIssue{
+				{Path: "post.md", Line: 7, Rule: "embed", Message: "link リンク is not a known embed, push sends it as text"},
+				{Path: "post.md", Line: 8, Rule: "image", Message: "missing.png does not exist"},
+				{Path: "post.md", Line: 8, Rule: "local-image", Message: "missing.png cannot be uploaded, push skips it"},
+			},
+		},
+		{
+			name:    "AsciiDoc でも埋め込みにならないリンクを返す",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\nformat: asciidoc\n---\n\n== 見出し\nhttps://example.com[リンク]\n",
+			want: []LintIssue{
+				{Path: "post.md", Line: 9, Rule: "embed", Message: "link リンク is not a known embed, push sends it as text"},
+			},
+		},
+		{
+			name:    "ブロックの JSON が壊れていれば問題にする",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\nformat: blocks\n---\n\nnot json\n",
+			want: []LintIssue{
+				{Path: "post.md", Rule: "format", Message: `invalid blocks: decode PostBody: "{" expected: unexpected byte 110 'n' at 0`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// execute
+			issues, _, err := LintFile("post.md", tt.content, tt.opts)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, issues)
+		})
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	dir := t.TempDir()
+	content := "---\nid: \"1\"\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\n![image1](image1.png)\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte(content), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.md"), []byte(content), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "image1.png"), []byte{}, 0644))
+
+	// execute
+	issues, err := LintFiles([]string{dir}, LintOptions{})
+
+	// verify
+	assert.NoError(t, err)
+	assert.Equal(t, []LintIssue{
+		{Path: filepath.Join(dir, "a.md"), Line: 8, Rule: "local-image", Message: "image1.png cannot be uploaded, push skips it"},
+		{Path: filepath.Join(dir, "b.md"), Line: 8, Rule: "local-image", Message: "image1.png cannot be uploaded, push skips it"},
+		{Path: filepath.Join(dir, "b.md"), Rule: "duplicate-id", Message: "id 1 is also used in " + filepath.Join(dir, "a.md")},
+	}, issues)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// list で表示する投稿
+// LocalPath は手元にその投稿のファイルがあるときだけ付く
+type ListedPost struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Status      string `json:"status"`
+	Fee         int    `json:"fee"`
+	PublishedAt string `json:"published_at"`
+	UpdatedAt   string `json:"updated_at"`
+	LocalPath   string `json:"local_path,omitempty"`
+}
+
+// 並べ替えに使う項目
+type ListSort string
+
+const (
+	ListSortPublished ListSort = "published"
+	ListSortUpdated   ListSort = "updated"
+	ListSortFee       ListSort = "fee"
+	ListSortTitle     ListSort = "title"
+	ListSortID        ListSort = "id"
+)
+
+func (s ListSort) Validate() error {
+	switch s {
+	case ListSortPublished, ListSortUpdated, ListSortFee, ListSortTitle, ListSortID:
+		return nil
+	default:
+		return fmt.Errorf("invalid sort: %s", s)
+	}
+}
+
+// 一覧の投稿を filter で絞り込み、手元のファイルのパスを付ける
+// localPaths には投稿の ID ごとのファイルのパスを渡す
+func NewListedPosts(posts []fanboxgo.Post, filter *PullFilter, localPaths map[string]string) []ListedPost {
+	listed := []ListedPost{}
+	for _, post := range posts {
+		if !filter.Match(post) {
+			continue
+		}
+		listed = append(listed, ListedPost{
+			ID:          post.ID.Value,
+			Title:       post.Title.Value,
+			Status:      string(post.Status.Value),
+			Fee:         post.FeeRequired.Value,
+			PublishedAt: post.PublishedAt.Value,
+			UpdatedAt:   post.UpdatedAt.Value,
+			LocalPath:   localPaths[post.ID.Value],
+		})
+	}
+	return listed
+}
+
+// dir の中の投稿のファイルを読み、ID ごとのパスを返す
+// 読めないファイルは list の邪魔をしないよう飛ばす
+func localPostPaths(dir string) (map[string]string, error) {
+	files, err := findPostFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]string{}
+	for _, file := range files {
+		m, _, err := readFile(file)
+		if err != nil {
+			slog.Debug("skipped unreadable file", "path", file, "error", err)
+			continue
+		}
+		if m.Id == "" {
+			continue
+		}
+		paths[m.Id] = file
+	}
+	return paths, nil
+}
+
+// 投稿を並べ替える
+// 日時は RFC3339 の文字列のまま比べ、同じ値なら ID の順にする
+func SortListedPosts(posts []ListedPost, key ListSort, reverse bool) error {
+	err := key.Validate()
+	if err != nil {
+		return err
+	}
+
+	slices.SortStableFunc(posts, func(a, b ListedPost) int {
+		var c int
+		switch key {
+		case ListSortPublished:
+			c = strings.Compare(a.PublishedAt, b.PublishedAt)
+		case ListSortUpdated:
+			c = strings.Compare(a.UpdatedAt, b.UpdatedAt)
+		case ListSortFee:
+			c = a.Fee - b.Fee
+		case ListSortTitle:
+			c = strings.Compare(a.Title, b.Title)
+		}
+		if c == 0 {
+			c = compareID(a.ID, b.ID)
+		}
+		if reverse {
+			return -c
+		}
+		return c
+	})
+	return nil
+}
+
+// ID は数字なので、桁数が違っても数として比べる
+func compareID(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// 人が読むための表として書き出す
+func WriteListTable(w io.Writer, posts []ListedPost) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tSTATUS\tFEE\tPUBLISHED\tUPDATED\tLOCAL")
+	for _, post := range posts {
+		local := post.LocalPath
+		if local == "" {
+			local = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", post.ID, post.Title, post.Status, post.Fee, post.PublishedAt, post.UpdatedAt, local)
+	}
+	return tw.Flush()
+}
+
+func WriteListCSV(w io.Writer, posts []ListedPost) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "title", "status", "fee", "published_at", "updated_at", "local_path"})
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		err := cw.Write([]string{post.ID, post.Title, post.Status, strconv.Itoa(post.Fee), post.PublishedAt, post.UpdatedAt, post.LocalPath})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
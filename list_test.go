@@ -0,0 +1,158 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewListedPosts(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	posts := []fanboxgo.Post{
+		{
+			ID:          fanboxgo.NewOptString("1000000"),
+			Title:       fanboxgo.NewOptString("公開済み"),
+			Status:      fanboxgo.NewOptPostStatus(fanboxgo.PostStatusPublished),
+			FeeRequired: fanboxgo.NewOptInt(500),
+			PublishedAt: fanboxgo.NewOptString("2024-05-10T12:00:00+09:00"),
+			UpdatedAt:   fanboxgo.NewOptString("2024-05-11T12:00:00+09:00"),
+		},
+		{
+			ID:     fanboxgo.NewOptString("1000001"),
+			Title:  fanboxgo.NewOptString("下書き"),
+			Status: fanboxgo.NewOptPostStatus(fanboxgo.PostStatusDraft),
+		},
+	}
+	filter, err := NewPullFilter("", "published", "", "", "", "")
+	assert.NoError(t, err)
+
+	// execute
+	listed := NewListedPosts(posts, filter, map[string]string{"1000000": "post.md"})
+
+	// verify
+	assert.Equal(t, []ListedPost{
+		{
+			ID:          "1000000",
+			Title:       "公開済み",
+			Status:      "published",
+			Fee:         500,
+			PublishedAt: "2024-05-10T12:00:00+09:00",
+			UpdatedAt:   "2024-05-11T12:00:00+09:00",
+			LocalPath:   "post.md",
+		},
+	}, listed)
+}
+
+func TestSortListedPosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     ListSort
+		reverse bool
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "公開日時の古い順",
+			key:  ListSortPublished,
+			want: []string{"999999", "1000001", "1000000"},
+		},
+		{
+			name:    "公開日時の新しい順",
+			key:     ListSortPublished,
+			reverse: true,
+			want:    []string{"1000000", "1000001", "999999"},
+		},
+		{
+			name: "金額が同じなら ID を数として比べる",
+			key:  ListSortFee,
+			want: []string{"999999", "1000000", "1000001"},
+		},
+		{
+			name: "タイトル順",
+			key:  ListSortTitle,
+			want: []string{"1000001", "999999", "1000000"},
+		},
+		{
+			name:    "知らない項目はエラーにする",
+			key:     ListSort("author"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			posts := []ListedPost{
+				{ID: "1000000", Title: "c", Fee: 500, PublishedAt: "2024-05-10T12:00:00+09:00"},
+				{ID: "1000001", Title: "a", Fee: 500, PublishedAt: "2024-05-01T12:00:00+09:00"},
+				{ID: "999999", Title: "b", Fee: 0, PublishedAt: "2024-04-01T12:00:00+09:00"},
+			}
+
+			// execute
+			err := SortListedPosts(posts, tt.key, tt.reverse)
+
+			// verify
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			ids := []string{}
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	posts := []ListedPost{
+		{ID: "1000000", Title: "カンマ, を含む", Status: "published", Fee: 500, PublishedAt: "2024-05-10T12:00:00+09:00", UpdatedAt: "2024-05-11T12:00:00+09:00", LocalPath: "post.md"},
+		{ID: "1000001", Title: "下書き", Status: "draft"},
+	}
+
+	tests := []struct {
+		name  string
+		write func(b *bytes.Buffer) error
+		want  string
+	}{
+		{
+			name:  "CSV は見出しの行を付けて引用する",
+			write: func(b *bytes.Buffer) error { return WriteListCSV(b, posts) },
+			want: "id,title,status,fee,published_at,updated_at,local_path\n" +
+				"1000000,\"カンマ, を含む\",published,500,2024-05-10T12:00:00+09:00,2024-05-11T12:00:00+09:00,post.md\n" +
+				"1000001,下書き,draft,0,,,\n",
+		},
+		{
+			name:  "表は手元に無ければ - にする",
+			write: func(b *bytes.Buffer) error { return WriteListTable(b, posts) },
+			want: "ID       TITLE     STATUS     FEE  PUBLISHED                  UPDATED                    LOCAL\n" +
+				"1000000  カンマ, を含む  published  500  2024-05-10T12:00:00+09:00  2024-05-11T12:00:00+09:00  post.md\n" +
+				"1000001  下書き       draft      0                                                          -\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			var b bytes.Buffer
+
+			// execute
+			err := tt.write(&b)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
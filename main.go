@@ -2,9 +2,15 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
+	fanboxgo "github.com/defaultcf/fanbox-go"
+	"github.com/defaultcf/fanboxsync/fanbox"
 	"github.com/urfave/cli/v2"
 )
 
@@ -13,39 +19,165 @@ func main() {
 		Name:    "fanboxsync",
 		Usage:   "Sync FANBOX posts",
 		Version: version,
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "output", Usage: "format of command results (text, json)", Value: string(OutputFormatText)},
+			&cli.BoolFlag{Name: "verbose", Usage: "also log debug messages"},
+			&cli.BoolFlag{Name: "quiet", Usage: "log only errors"},
+		},
+		Before: func(ctx *cli.Context) error {
+			format := OutputFormat(ctx.String("output"))
+			err := format.Validate()
+			if err != nil {
+				return err
+			}
+			SetOutput(NewOutput(format, os.Stdout))
+			slog.SetDefault(newLogger(os.Stderr, ctx.Bool("verbose"), ctx.Bool("quiet")))
+			return nil
+		},
 		Commands: []*cli.Command{
 			commandPull,
+			commandArchive,
 			commandCreate,
 			commandPush,
 			commandDelete,
+			commandPublish,
+			commandUnpublish,
+			commandHistory,
+			commandRollback,
+			commandExport,
+			commandHTML,
+			commandEPUB,
+			commandImport,
+			commandBackup,
+			commandRestore,
+			commandLint,
+			commandWatch,
+			commandPreview,
+			commandList,
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		output.Error(err)
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+var flagOffline = &cli.BoolFlag{
+	Name:  "offline",
+	Usage: "resolve embeds only from the local cache",
+}
+
+var flagEmbedCacheTTL = &cli.DurationFlag{
+	Name:  "embed-cache-ttl",
+	Usage: "how long resolved embed URLs are cached",
+	Value: 30 * 24 * time.Hour,
+}
+
+var flagDryRun = &cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "print the requests that would be sent without calling the API",
+}
+
+// 対象の投稿を絞り込むフラグ
+// コマンドごとに別のスライスにしないと、append で他のコマンドのフラグを書き換えてしまう
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "id", Usage: "only the post with this ID"},
+		&cli.StringFlag{Name: "status", Usage: "only posts with this status (draft, published)"},
+		&cli.StringFlag{Name: "since", Usage: "only posts published on or after this date (YYYY-MM-DD or RFC3339)"},
+		&cli.StringFlag{Name: "until", Usage: "only posts published on or before this date (YYYY-MM-DD or RFC3339)"},
+		&cli.StringFlag{Name: "fee", Usage: "only posts with this fee"},
+		&cli.StringFlag{Name: "tag", Usage: "only posts with this tag (fetches each post to read its tags)"},
 	}
 }
 
+func newPullFilterFromContext(ctx *cli.Context) (*PullFilter, error) {
+	return NewPullFilter(
+		ctx.String("id"),
+		ctx.String("status"),
+		ctx.String("since"),
+		ctx.String("until"),
+		ctx.String("fee"),
+		ctx.String("tag"),
+	)
+}
+
 var commandPull = &cli.Command{
 	Name:  "pull",
 	Usage: "Pull posts from FANBOX",
+	Flags: append(filterFlags(),
+		flagOffline,
+		flagEmbedCacheTTL,
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "body format of the saved files (markdown, asciidoc or blocks)",
+			Value: BodyFormatMarkdown,
+		},
+	),
 	Action: func(ctx *cli.Context) error {
-		log.Print("pull")
+		slog.Debug("pull")
 		config, err := newConfig()
 		if err != nil {
 			return err
 		}
 
-		err = CommandPull(config)
+		filter, err := newPullFilterFromContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		iframelyClient, err := newIframelyClient(ctx.Bool("offline"), ctx.Duration("embed-cache-ttl"))
+		if err != nil {
+			return err
+		}
+
+		err = CommandPull(config, filter, ctx.String("format"), iframelyClient)
+		return err
+	},
+}
+
+var commandArchive = &cli.Command{
+	Name:      "archive",
+	Usage:     "Pull public posts of any creator (read-only)",
+	ArgsUsage: "<creator_id>",
+	Flags: []cli.Flag{
+		flagOffline,
+		flagEmbedCacheTTL,
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("archive")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		creatorId := ctx.Args().Get(0)
+		if creatorId == "" {
+			return fmt.Errorf("creator_id is empty")
+		}
+
+		iframelyClient, err := newIframelyClient(ctx.Bool("offline"), ctx.Duration("embed-cache-ttl"))
+		if err != nil {
+			return err
+		}
+
+		f := fanbox.NewPublicFanbox(&http.Client{}, config.Default.SessionId, userAgent())
+		err = CommandArchive(f, creatorId, iframelyClient)
 		return err
 	},
 }
 
 var commandCreate = &cli.Command{
-	Name:  "create",
-	Usage: "Create post",
+	Name:      "create",
+	Usage:     "Create post",
+	ArgsUsage: "<title | path/to/new.md>",
+	Flags: []cli.Flag{
+		flagDryRun,
+	},
 	Action: func(ctx *cli.Context) error {
-		log.Print("create")
+		slog.Debug("create")
 		config, err := newConfig()
 		if err != nil {
 			return err
@@ -56,39 +188,489 @@ var commandCreate = &cli.Command{
 			return fmt.Errorf("title is empty")
 		}
 
-		err = CommandCreate(config, title)
+		f, err := newFanbox(config, ctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+
+		// Markdown のファイルが渡されたら、その内容で投稿を作る
+		if strings.HasSuffix(title, ".md") {
+			if _, err := os.Stat(title); err == nil {
+				return CommandCreateFromFile(f, title, ctx.Bool("dry-run"))
+			}
+		}
+
+		err = CommandCreate(f, title, ctx.Bool("dry-run"))
 		return err
 	},
 }
 
 var commandPush = &cli.Command{
-	Name:  "push",
-	Usage: "Push post",
+	Name:        "push",
+	Usage:       "Push post",
+	Description: "Merging with a post updated after pull is not supported, because the version at pull is not kept. Use --diff to see the difference and --force to overwrite it.",
+	Flags: []cli.Flag{
+		flagDryRun,
+		&cli.BoolFlag{Name: "force", Usage: "overwrite the post even if it was updated after pull"},
+		&cli.BoolFlag{Name: "diff", Usage: "show the difference from the remote post on conflict"},
+	},
 	Action: func(ctx *cli.Context) error {
-		log.Print("push")
+		slog.Debug("push")
 		config, err := newConfig()
 		if err != nil {
 			return err
 		}
 
+		f, err := newFanbox(config, ctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+
 		path := ctx.Args().Get(0)
-		err = CommandPush(config, path)
+		err = CommandPush(f, path, PushOptions{
+			DryRun: ctx.Bool("dry-run"),
+			Force:  ctx.Bool("force"),
+			Diff:   ctx.Bool("diff"),
+		})
 		return err
 	},
 }
 
 var commandDelete = &cli.Command{
-	Name:  "delete",
-	Usage: "Delete post",
+	Name:      "delete",
+	Usage:     "Delete post",
+	ArgsUsage: "<path | id>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "yes", Aliases: []string{"y"}, Usage: "delete without confirmation"},
+		flagDryRun,
+		&cli.BoolFlag{Name: "force", Usage: "allow deleting published posts"},
+		&cli.StringFlag{Name: "trash", Usage: "move the local file to this directory after deleting"},
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("delete")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		target := ctx.Args().Get(0)
+		if target == "" {
+			return fmt.Errorf("path or id is empty")
+		}
+
+		f, err := newFanbox(config, ctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+		err = CommandDelete(f, target, DeleteOptions{
+			Yes:      ctx.Bool("yes"),
+			DryRun:   ctx.Bool("dry-run"),
+			Force:    ctx.Bool("force"),
+			TrashDir: ctx.String("trash"),
+			Dir:      ".",
+		})
+		return err
+	},
+}
+
+// 予約投稿の schedule は無い。fanbox-go の post.update は予約のステータスも公開日時も送れない
+var commandPublish = &cli.Command{
+	Name:        "publish",
+	Usage:       "Publish post without changing its body",
+	Description: "Scheduling a publish time is not supported yet, because fanbox-go cannot send a reserved status or a publish time.",
+	ArgsUsage:   "<path | id>",
+	Flags: []cli.Flag{
+		flagDryRun,
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("publish")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		target := ctx.Args().Get(0)
+		err = CommandUpdateStatus(config, target, fanboxgo.PostStatusPublished, ctx.Bool("dry-run"))
+		return err
+	},
+}
+
+var commandUnpublish = &cli.Command{
+	Name:      "unpublish",
+	Usage:     "Turn post back into draft without changing its body",
+	ArgsUsage: "<path | id>",
+	Flags: []cli.Flag{
+		flagDryRun,
+	},
 	Action: func(ctx *cli.Context) error {
-		log.Print("delete")
+		slog.Debug("unpublish")
 		config, err := newConfig()
 		if err != nil {
 			return err
 		}
 
+		target := ctx.Args().Get(0)
+		err = CommandUpdateStatus(config, target, fanboxgo.PostStatusDraft, ctx.Bool("dry-run"))
+		return err
+	},
+}
+
+var commandHistory = &cli.Command{
+	Name:      "history",
+	Usage:     "List saved revisions of post",
+	ArgsUsage: "<path | id>",
+	Action: func(ctx *cli.Context) error {
+		target := ctx.Args().Get(0)
+		return CommandHistory(target)
+	},
+}
+
+var commandRollback = &cli.Command{
+	Name:      "rollback",
+	Usage:     "Restore post to saved revision, keeping its current status unless --restore-status is given",
+	ArgsUsage: "<path | id> <revision>",
+	Flags: []cli.Flag{
+		flagDryRun,
+		&cli.BoolFlag{Name: "restore-status", Usage: "also restore the status saved in the revision"},
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("rollback")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		target := ctx.Args().Get(0)
+		revision := ctx.Args().Get(1)
+		if revision == "" {
+			return fmt.Errorf("revision is empty")
+		}
+
+		f, err := newFanbox(config, ctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+		err = CommandRollback(f, target, revision, RollbackOptions{
+			DryRun:        ctx.Bool("dry-run"),
+			RestoreStatus: ctx.Bool("restore-status"),
+		})
+		return err
+	},
+}
+
+var commandBackup = &cli.Command{
+	Name:      "backup",
+	Usage:     "Back up all posts as JSON with their tags, images and files",
+	ArgsUsage: "[output.tar.gz]",
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("backup")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		outPath := ctx.Args().Get(0)
+		if outPath == "" {
+			outPath = fmt.Sprintf("fanboxsync-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+
+		err = CommandBackup(config, outPath)
+		return err
+	},
+}
+
+var commandRestore = &cli.Command{
+	Name:        "restore",
+	Usage:       "Recreate posts as drafts from backup",
+	Description: "Images and files cannot be uploaded, so they are skipped with a warning. Embeds other than FANBOX posts are restored as links.",
+	ArgsUsage:   "<backup.tar.gz>",
+	Flags: []cli.Flag{
+		flagDryRun,
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("restore")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		archivePath := ctx.Args().Get(0)
+		if archivePath == "" {
+			return fmt.Errorf("backup path is empty")
+		}
+
+		f, err := newFanbox(config, ctx.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+
+		err = CommandRestore(f, archivePath, ctx.Bool("dry-run"))
+		return err
+	},
+}
+
+var commandLint = &cli.Command{
+	Name:      "lint",
+	Usage:     "Check post files before pushing",
+	ArgsUsage: "[paths...]",
+	Flags: []cli.Flag{
+		&cli.IntSliceFlag{Name: "plan", Usage: "fee of plan that paid posts must match (can be repeated)"},
+		&cli.BoolFlag{Name: "remote", Usage: "check that images are uploaded to FANBOX"},
+		&cli.BoolFlag{Name: "json", Usage: "deprecated, use the global --output json"},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Bool("json") {
+			slog.Warn("lint --json is deprecated, use --output json")
+			SetOutput(NewOutput(OutputFormatJSON, os.Stdout))
+		}
+
+		// CI でも動くよう、--remote のときだけ config を読む
+		var config *config
+		if ctx.Bool("remote") {
+			var err error
+			config, err = newConfig()
+			if err != nil {
+				return err
+			}
+		}
+
+		opts := LintOptions{
+			Plans: ctx.IntSlice("plan"),
+		}
+		return CommandLint(config, ctx.Args().Slice(), opts)
+	},
+}
+
+var commandWatch = &cli.Command{
+	Name:      "watch",
+	Usage:     "Push drafts automatically when they are saved",
+	ArgsUsage: "[dir]",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{Name: "debounce", Usage: "wait this long after the last save before pushing", Value: time.Second},
+		&cli.DurationFlag{Name: "max-backoff", Usage: "longest wait before retrying a failed push", Value: 5 * time.Minute},
+		&cli.BoolFlag{Name: "published", Usage: "also push published posts"},
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("watch")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		dir := ctx.Args().Get(0)
+		if dir == "" {
+			dir = "."
+		}
+
+		// Ctrl-C で止める
+		watchCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+		defer stop()
+
+		opts := watchOptions{
+			WatchOptions: WatchOptions{
+				Debounce:   ctx.Duration("debounce"),
+				MinBackoff: time.Second,
+				MaxBackoff: ctx.Duration("max-backoff"),
+			},
+			Published: ctx.Bool("published"),
+		}
+		f, err := newFanbox(config, false)
+		if err != nil {
+			return err
+		}
+		return CommandWatch(watchCtx, f, dir, opts)
+	},
+}
+
+var commandPreview = &cli.Command{
+	Name:      "preview",
+	Usage:     "Preview post as it will be pushed",
+	ArgsUsage: "<file>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "addr", Usage: "address to listen on", Value: "localhost:8080"},
+	},
+	Action: func(ctx *cli.Context) error {
 		path := ctx.Args().Get(0)
-		err = CommandDelete(config, path)
+		if path == "" {
+			return fmt.Errorf("file path is empty")
+		}
+
+		// Ctrl-C で止める
+		previewCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+		defer stop()
+
+		return CommandPreview(previewCtx, path, ctx.String("addr"))
+	},
+}
+
+var commandList = &cli.Command{
+	Name:  "list",
+	Usage: "List posts on FANBOX",
+	Flags: append(filterFlags(),
+		&cli.StringFlag{Name: "sort", Usage: "sort by published, updated, fee, title or id", Value: string(ListSortPublished)},
+		&cli.BoolFlag{Name: "reverse", Usage: "sort in descending order"},
+		&cli.StringFlag{Name: "dir", Usage: "directory to look for local files", Value: "."},
+		&cli.BoolFlag{Name: "csv", Usage: "print as CSV, cannot be used with --output json"},
+	),
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("list")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		filter, err := newPullFilterFromContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		f, err := newFanbox(config, false)
+		if err != nil {
+			return err
+		}
+		opts := ListOptions{
+			Sort:    ListSort(ctx.String("sort")),
+			Reverse: ctx.Bool("reverse"),
+			Dir:     ctx.String("dir"),
+			CSV:     ctx.Bool("csv"),
+		}
+		return CommandList(f, filter, opts)
+	},
+}
+
+var commandExport = &cli.Command{
+	Name:      "export",
+	Usage:     "Export posts for static site generators",
+	ArgsUsage: "<output_dir>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "format", Usage: "site generator (hugo, jekyll)", Value: string(SiteFormatHugo)},
+		&cli.StringFlag{Name: "paid", Usage: "how to export paid posts (keep, skip, teaser). keep publishes the paid body", Value: string(PaidPolicyTeaser)},
+		&cli.IntFlag{Name: "teaser-length", Usage: "number of lines kept in teaser", Value: 5},
+		&cli.StringFlag{Name: "teaser-note", Usage: "text appended to teaser", Value: "続きは FANBOX で公開しています。"},
+		flagOffline,
+		flagEmbedCacheTTL,
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("export")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		outDir := ctx.Args().Get(0)
+		if outDir == "" {
+			return fmt.Errorf("output_dir is empty")
+		}
+
+		iframelyClient, err := newIframelyClient(ctx.Bool("offline"), ctx.Duration("embed-cache-ttl"))
+		if err != nil {
+			return err
+		}
+
+		err = CommandExport(config, outDir, ExportOptions{
+			Format:       SiteFormat(ctx.String("format")),
+			Paid:         PaidPolicy(ctx.String("paid")),
+			TeaserLength: ctx.Int("teaser-length"),
+			TeaserNote:   ctx.String("teaser-note"),
+		}, iframelyClient)
+		return err
+	},
+}
+
+var commandHTML = &cli.Command{
+	Name:      "html",
+	Usage:     "Export posts as HTML pages",
+	ArgsUsage: "<output_dir>",
+	Flags: append(filterFlags(),
+		flagOffline,
+		flagEmbedCacheTTL,
+	),
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("html")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		outDir := ctx.Args().Get(0)
+		if outDir == "" {
+			return fmt.Errorf("output_dir is empty")
+		}
+
+		filter, err := newPullFilterFromContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		iframelyClient, err := newIframelyClient(ctx.Bool("offline"), ctx.Duration("embed-cache-ttl"))
+		if err != nil {
+			return err
+		}
+
+		err = CommandHTML(config, outDir, filter, iframelyClient)
+		return err
+	},
+}
+
+var commandEPUB = &cli.Command{
+	Name:      "epub",
+	Usage:     "Collect posts into an EPUB",
+	ArgsUsage: "<output.epub>",
+	Flags: append(filterFlags(),
+		&cli.StringFlag{Name: "title", Usage: "title of the book", Value: "FANBOX"},
+		flagOffline,
+		flagEmbedCacheTTL,
+	),
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("epub")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		outPath := ctx.Args().Get(0)
+		if outPath == "" {
+			return fmt.Errorf("output path is empty")
+		}
+
+		filter, err := newPullFilterFromContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		iframelyClient, err := newIframelyClient(ctx.Bool("offline"), ctx.Duration("embed-cache-ttl"))
+		if err != nil {
+			return err
+		}
+
+		err = CommandEPUB(config, outPath, ctx.String("title"), filter, iframelyClient)
+		return err
+	},
+}
+
+var commandImport = &cli.Command{
+	Name:        "import",
+	Usage:       "Create drafts from posts exported from other platforms",
+	Description: "Patreon CSV exports and Ci-en exports are not supported; use the Patreon API JSON, or save Ci-en posts as HTML files. Images cannot be uploaded, so upload them on FANBOX and replace each image line with its image id before push.",
+	ArgsUsage:   "<path>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "format", Usage: "source format (html, note, patreon); patreon reads the API JSON, not the CSV export, and Ci-en posts can be imported as saved HTML files", Required: true},
+		&cli.StringFlag{Name: "report", Usage: "write a CSV mapping source IDs to FANBOX IDs"},
+		flagDryRun,
+	},
+	Action: func(ctx *cli.Context) error {
+		slog.Debug("import")
+		config, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		path := ctx.Args().Get(0)
+		if path == "" {
+			return fmt.Errorf("path is empty")
+		}
+
+		err = CommandImport(config, ImportFormat(ctx.String("format")), path, ctx.String("report"), ctx.Bool("dry-run"))
 		return err
 	},
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// コマンドの結果の書き出し方
+type OutputFormat string
+
+const (
+	OutputFormatText OutputFormat = "text"
+	OutputFormatJSON OutputFormat = "json"
+)
+
+func (f OutputFormat) Validate() error {
+	switch f {
+	case OutputFormatText, OutputFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format: %s", f)
+	}
+}
+
+// コマンドが何をしたか
+// json では 1 件ごとに 1 行の JSON になるので、使わない項目は省く
+type Result struct {
+	Action    string `json:"action"`
+	ID        string `json:"id,omitempty"`
+	SourceID  string `json:"source_id,omitempty"` // import や restore の元の ID
+	Path      string `json:"path,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Message   string `json:"message,omitempty"`
+	DryRun    bool   `json:"dry_run,omitempty"`
+}
+
+// コマンドの結果を標準出力に書き出す
+// ログは標準エラー出力に出すので、結果だけをスクリプトから読める
+type Output struct {
+	Format OutputFormat
+	w      io.Writer
+}
+
+func NewOutput(format OutputFormat, w io.Writer) *Output {
+	return &Output{Format: format, w: w}
+}
+
+// コマンドの結果の書き出し先
+// main で --output に合わせて差し替える
+var output = NewOutput(OutputFormatText, os.Stdout)
+
+// コマンドの結果の書き出し先を差し替える
+func SetOutput(o *Output) {
+	output = o
+}
+
+// 結果を 1 件書き出す
+// text なら format で作った 1 行を、json なら result を 1 行の JSON として書く
+func (o *Output) Print(result any, format string, args ...any) error {
+	if o.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(o.w)
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(result)
+	}
+	_, err := fmt.Fprintf(o.w, format+"\n", args...)
+	return err
+}
+
+// コマンドが失敗したことを書き出す
+// text ならログに出るだけなので、json のときだけ結果として書く
+func (o *Output) Error(err error) {
+	if o.Format != OutputFormatJSON {
+		return
+	}
+	_ = o.Print(Result{Action: "error", Message: err.Error()}, "")
+}
+
+// dry-run で送るはずだったリクエストの書き出し先
+// json では結果の行に混ざらないよう、標準エラー出力に書く
+func (o *Output) DryRunWriter() io.Writer {
+	if o.Format == OutputFormatJSON {
+		return os.Stderr
+	}
+	return o.w
+}
+
+// ログの出し方
+// log パッケージのログも、この設定で Info として出る
+func newLogger(w io.Writer, verbose bool, quiet bool) *slog.Logger {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	if quiet {
+		level = slog.LevelError
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		// CLI のログなので時刻は要らない
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+}
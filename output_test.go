@@ -0,0 +1,81 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format OutputFormat
+		want   string
+	}{
+		{
+			name:   "text なら 1 行の文を書く",
+			format: OutputFormatText,
+			want:   "pushed 1000000 from post.md\n",
+		},
+		{
+			name:   "json なら 1 行の JSON を書く",
+			format: OutputFormatJSON,
+			want:   `{"action":"pushed","id":"1000000","path":"post.md","title":"<テスト>"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			var b bytes.Buffer
+			o := NewOutput(tt.format, &b)
+
+			// execute
+			err := o.Print(Result{Action: "pushed", ID: "1000000", Path: "post.md", Title: "<テスト>"}, "pushed %s from %s", "1000000", "post.md")
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func TestOutputError(t *testing.T) {
+	tests := []struct {
+		name   string
+		format OutputFormat
+		want   string
+	}{
+		{
+			name:   "text ならログに任せて何も書かない",
+			format: OutputFormatText,
+			want:   "",
+		},
+		{
+			name:   "json ならエラーを結果として書く",
+			format: OutputFormatJSON,
+			want:   `{"action":"error","message":"failed"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			var b bytes.Buffer
+			o := NewOutput(tt.format, &b)
+
+			// execute
+			o.Error(errors.New("failed"))
+
+			// verify
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
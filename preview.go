@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	fanboxgo "github.com/defaultcf/fanbox-go"
+)
+
+// push する前に、送られるブロックから投稿を表示するサーバー
+// ファイルが変わったら Reload を呼ぶと、開いているブラウザが読み込み直す
+type PreviewServer struct {
+	path    string
+	mux     *http.ServeMux
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="UTF-8">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; background: #f5f5f5; color: #222; font-family: "Hiragino Sans", "Noto Sans JP", sans-serif; }
+.meta { max-width: 680px; margin: 0 auto; padding: 16px 0; color: #8c8c8c; font-size: 12px; }
+.meta span { margin-right: 8px; padding: 2px 8px; border-radius: 4px; background: #e6e6e6; }
+article { max-width: 680px; margin: 0 auto 64px; padding: 40px 48px; background: #fff; border-radius: 8px; }
+h1 { margin: 0 0 40px; font-size: 24px; line-height: 1.5; }
+h2 { margin: 40px 0 16px; font-size: 20px; line-height: 1.5; }
+p { margin: 0; min-height: 2em; font-size: 16px; line-height: 2; white-space: pre-wrap; word-break: break-word; }
+figure { margin: 24px 0; }
+figure img { display: block; max-width: 100%; margin: 0 auto; }
+.embed { margin: 24px 0; padding: 16px; border: 1px solid #e6e6e6; border-radius: 8px; }
+.embed a { color: #0096fa; }
+.error { color: #d33; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<div class="meta">{{range .Labels}}<span>{{.}}</span>{{end}}</div>
+<article>
+{{.Body}}
+</article>
+<script>new EventSource("/events").onmessage = () => location.reload();</script>
+</body>
+</html>
+`))
+
+func NewPreviewServer(path string) *PreviewServer {
+	s := &PreviewServer{
+		path:    path,
+		mux:     http.NewServeMux(),
+		clients: map[chan struct{}]struct{}{},
+	}
+	s.mux.HandleFunc("/", s.handlePage)
+	s.mux.HandleFunc("/events", s.handleEvents)
+	// 本文から相対パスで参照した画像を返す
+	files := http.StripPrefix("/files/", http.FileServer(http.Dir(filepath.Dir(path))))
+	s.mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		// 同じディレクトリの下書きや設定を読ませないよう、画像だけを返す
+		if !previewImageExts[strings.ToLower(filepath.Ext(r.URL.Path))] {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+	return s
+}
+
+var previewImageExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+	".svg":  true,
+}
+
+func (s *PreviewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// 開いているブラウザに読み込み直させる
+func (s *PreviewServer) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *PreviewServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	title, labels, body, err := s.render()
+	if err != nil {
+		// 書きかけで読めないときも、直せば読み込み直せるようにページとして返す
+		title = filepath.Base(s.path)
+		labels = nil
+		body = fmt.Sprintf(`<p class="error">%s</p>`, html.EscapeString(err.Error()))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = previewTemplate.Execute(w, struct {
+		Title  string
+		Labels []string
+		Body   template.HTML
+	}{
+		Title:  title,
+		Labels: labels,
+		Body:   template.HTML(body),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *PreviewServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	client := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// ファイルを読み、push で送るのと同じブロックから HTML にする
+func (s *PreviewServer) render() (string, []string, string, error) {
+	m, body, err := readFile(s.path)
+	if err != nil {
+		return "", nil, "", err
+	}
+	entry := newEntryFromMeta(m, body)
+	post, err := entry.ConvertFanbox(entry)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	labels := []string{m.Status, fmt.Sprintf("%d 円", post.FeeRequired.Value)}
+
+	// 送られるのは画像や埋め込みの ID だけなので、本文に書いた画像の場所と pull した埋め込みの URL から表示する
+	// 埋め込みにならないリンクは、push と同じく書いたままの段落になる
+	images := map[string]string{}
+	for imageId, image := range post.Body.Value.ImageMap.Value {
+		images[imageId] = previewImageSrc(image.OriginalUrl.Value)
+	}
+	embeds := maps.Clone(post.Body.Value.UrlEmbedMap.Value)
+	if embeds == nil {
+		embeds = fanboxgo.PostBodyUrlEmbedMap{}
+	}
+	for embedId, embedUrl := range entry.Embeds {
+		embeds[embedId] = fanboxgo.PostBodyUrlEmbedMapItem{
+			ID:   fanboxgo.NewOptString(embedId),
+			Type: fanboxgo.NewOptPostBodyUrlEmbedMapItemType(fanboxgo.PostBodyUrlEmbedMapItemTypeDefault),
+			URL:  fanboxgo.NewOptString(embedUrl),
+		}
+	}
+	post.Body.Value.UrlEmbedMap = fanboxgo.NewOptPostBodyUrlEmbedMap(embeds)
+
+	rendered, err := entry.ConvertHTML(post, images)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return entry.Title, labels, rendered, nil
+}
+
+// URL でなければ、ファイルからの相対パスとして /files/ から返す
+func previewImageSrc(src string) string {
+	if u, err := url.Parse(src); err == nil && u.Scheme != "" {
+		return src
+	}
+	return path.Join("/files", filepath.ToSlash(src))
+}
+
+// ファイルが変わったか確かめるため、絶対パスにしておく
+func previewPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
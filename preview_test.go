@@ -0,0 +1,182 @@
+package main_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreviewServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "送られるブロックから表示する",
+			file:    "post.md",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"500\"\nembeds:\n  abc: https://example.com\n---\n\n## 見出し\nこれは**太字**です\n![image1](image1.png)\n[abc](https://example.com)\n",
+			want: []string{
+				"<title>テスト投稿</title>",
+				"<span>draft</span><span>500 円</span>",
+				"<h2>見出し</h2>",
+				"<p>これは<strong>太字</strong>です</p>",
+				`<img src="/files/image1.png" alt="image1"/>`,
+				`<p class="embed"><a href="https://example.com">https://example.com</a></p>`,
+			},
+		},
+		{
+			name:    "埋め込みにならないリンクは、push と同じく書いたまま表示する",
+			file:    "post.md",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\n[リンク](https://example.com)\n[前回](https://creator.fanbox.cc/posts/123)\n",
+			want: []string{
+				"<p>[リンク](https://example.com)</p>",
+				`<p class="embed"><a href="https://creator.fanbox.cc/posts/123">https://creator.fanbox.cc/posts/123</a></p>`,
+			},
+		},
+		{
+			name:    "AsciiDoc の画像とリンクも、登録された書式で読む",
+			file:    "post.adoc",
+			content: "---\ntitle: テスト投稿\nstatus: draft\nfee: \"0\"\n---\n\nimage::image1.png[image1]\nhttps://example.com[リンク]\n",
+			want: []string{
+				`<img src="/files/image1.png" alt="image1"/>`,
+				"<p>[リンク](https://example.com)</p>",
+			},
+		},
+		{
+			name:    "読めないファイルはエラーを表示する",
+			file:    "post.md",
+			content: "---\ntitle: テスト投稿\n",
+			want: []string{
+				`<p class="error">`,
+				"front matter is not closed with ---",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			path := filepath.Join(t.TempDir(), tt.file)
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			server := httptest.NewServer(NewPreviewServer(path))
+			defer server.Close()
+
+			// execute
+			res, err := http.Get(server.URL)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+
+			// verify
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			for _, want := range tt.want {
+				assert.Contains(t, string(body), want)
+			}
+		})
+	}
+}
+
+func TestPreviewServerReload(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	path := filepath.Join(t.TempDir(), "post.md")
+	preview := NewPreviewServer(path)
+	server := httptest.NewServer(preview)
+	defer server.Close()
+	res, err := http.Get(server.URL + "/events")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	// execute
+	preview.Reload()
+
+	// verify
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "data: reload\n", line)
+}
+
+func TestPreviewServerFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantStatus int
+	}{
+		{
+			name:       "画像は返す",
+			file:       "image1.png",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "投稿のファイルは返さない",
+			file:       "post.md",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "画像以外のファイルは返さない",
+			file:       "config.toml",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			dir := t.TempDir()
+			for _, name := range []string{"post.md", "image1.png", "config.toml"} {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+			}
+			server := httptest.NewServer(NewPreviewServer(filepath.Join(dir, "post.md")))
+			defer server.Close()
+
+			// execute
+			res, err := http.Get(server.URL + "/files/" + tt.file)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			// verify
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestCommandPreviewStop(t *testing.T) {
+	t.Parallel()
+
+	// setup
+	path := filepath.Join(t.TempDir(), "post.md")
+	assert.NoError(t, os.WriteFile(path, []byte("---\ntitle: テスト投稿\n---\n"), 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- CommandPreview(ctx, path, "127.0.0.1:0")
+	}()
+
+	// execute
+	cancel()
+
+	// verify
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("preview did not stop")
+	}
+}
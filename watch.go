@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type WatchOptions struct {
+	Debounce   time.Duration // 最後の保存からこれだけ経ってから handle を呼ぶ
+	MinBackoff time.Duration // handle が失敗したときに、最初にやり直すまでの間隔
+	MaxBackoff time.Duration // やり直すまでの間隔の上限
+}
+
+// ディレクトリの中の投稿のファイルが保存されるたびに handle を呼ぶ
+// 保存が続く間は呼ばず、最後の保存から Debounce だけ経ってから呼ぶ
+// handle がエラーを返せば、間隔を倍にしながらやり直す
+// ctx が終わるまで返らない
+func Watch(ctx context.Context, dir string, opts WatchOptions, handle func(path string) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	fired := make(chan string)
+	timers := map[string]*time.Timer{}
+	backoffs := map[string]time.Duration{}
+	schedule := func(path string, delay time.Duration) {
+		if timer, exist := timers[path]; exist {
+			timer.Stop()
+		}
+		timers[path] = time.AfterFunc(delay, func() {
+			select {
+			case fired <- path:
+			case <-ctx.Done():
+			}
+		})
+	}
+	defer func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				// 後から作られたディレクトリも監視する
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watcher.Add(event.Name); err != nil {
+						slog.Warn("failed to watch directory", "path", event.Name, "error", err)
+					}
+					continue
+				}
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			if !isPostFile(event.Name) {
+				continue
+			}
+			schedule(event.Name, opts.Debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("watch error", "error", err)
+		case path := <-fired:
+			delete(timers, path)
+			err := handle(path)
+			if err == nil {
+				delete(backoffs, path)
+				continue
+			}
+
+			backoff := backoffs[path] * 2
+			if backoff < opts.MinBackoff {
+				backoff = opts.MinBackoff
+			}
+			if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+			backoffs[path] = backoff
+			slog.Warn("failed, retrying", "path", path, "error", err, "retry_in", backoff)
+			schedule(path, backoff)
+		}
+	}
+}
+
+// 本文の書式として登録された拡張子のファイルだけを対象にする
+// 隠しファイルは、エディタや WriteFileAtomic の一時ファイルなので除く
+func isPostFile(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	_, exist := bodyFormatExts[strings.ToLower(filepath.Ext(path))]
+	return exist
+}
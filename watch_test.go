@@ -0,0 +1,95 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/defaultcf/fanboxsync"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		writes    []string // 続けて保存するファイル
+		failures  int      // handle を失敗させる回数
+		wantCalls []string
+	}{
+		{
+			name:      "続けて保存しても 1 回だけ呼ぶ",
+			writes:    []string{"post.md", "post.md", "post.md"},
+			wantCalls: []string{"post.md"},
+		},
+		{
+			name:      "投稿のファイル以外は無視する",
+			writes:    []string{"memo.txt", ".post.md.123.tmp", "post.md"},
+			wantCalls: []string{"post.md"},
+		},
+		{
+			name:      "失敗したらやり直す",
+			writes:    []string{"post.md"},
+			failures:  2,
+			wantCalls: []string{"post.md", "post.md", "post.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// setup
+			dir := t.TempDir()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mu sync.Mutex
+			calls := []string{}
+			done := make(chan struct{})
+			handle := func(path string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, filepath.Base(path))
+				if len(calls) == len(tt.wantCalls) {
+					close(done)
+				}
+				if len(calls) <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			}
+			opts := WatchOptions{
+				Debounce:   50 * time.Millisecond,
+				MinBackoff: 10 * time.Millisecond,
+				MaxBackoff: 20 * time.Millisecond,
+			}
+			watchErr := make(chan error, 1)
+			go func() {
+				watchErr <- Watch(ctx, dir, opts, handle)
+			}()
+			time.Sleep(50 * time.Millisecond) // 監視を始めるまで待つ
+
+			// execute
+			for _, name := range tt.writes {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("content"), 0644))
+			}
+
+			// verify
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("handle was not called")
+			}
+			time.Sleep(200 * time.Millisecond) // 余計に呼ばれないことを確かめる
+			cancel()
+			assert.NoError(t, <-watchErr)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}